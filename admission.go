@@ -0,0 +1,85 @@
+package linker
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	// ConnInfo 连接建立时用于准入判断的信息，包含客户端发送的第一个数据包
+	ConnInfo struct {
+		Network    string
+		LocalAddr  string
+		RemoteAddr string
+		TLS        *tls.ConnectionState
+		Operator   uint32
+		Header     []byte
+		Body       []byte
+	}
+
+	// AcceptFunc 返回错误时拒绝连接，返回*StatusError可以指定响应的状态码
+	AcceptFunc func(ConnInfo) error
+)
+
+func newConnInfo(network, local, remote string, tlsState *tls.ConnectionState, rp Packet) ConnInfo {
+	return ConnInfo{
+		Network:    network,
+		LocalAddr:  local,
+		RemoteAddr: remote,
+		TLS:        tlsState,
+		Operator:   rp.Operator,
+		Header:     rp.Header,
+		Body:       rp.Body,
+	}
+}
+
+// GetRequestProperty 获取第一个数据包的请求属性
+func (ci ConnInfo) GetRequestProperty(key string) string {
+	values := strings.Split(string(ci.Header), ";")
+	for _, value := range values {
+		kv := strings.SplitN(value, "=", 2)
+		if len(kv) == 2 && kv[0] == key {
+			return kv[1]
+		}
+	}
+
+	return ""
+}
+
+// accept 执行准入判断，拒绝时返回需要发送给客户端的错误数据包
+func (s *Server) accept(info ConnInfo, rp Packet) (*Packet, error) {
+	if s.options.acceptHandler == nil {
+		return nil, nil
+	}
+
+	err := s.admit(info)
+	if err == nil {
+		return nil, nil
+	}
+
+	code, message := StatusForbidden, err.Error()
+	if se, ok := err.(*StatusError); ok {
+		code, message = se.Code, se.Message
+	}
+
+	header := []byte("code=" + strconv.Itoa(code) + ";message=" + propertyValue(message) + ";")
+	p, perr := NewPacket(rp.Operator, rp.Sequence, header, nil, s.options.pluginForPacketSender)
+	if perr != nil {
+		return nil, perr
+	}
+
+	return &p, err
+}
+
+// admit 执行准入函数，准入函数panic时拒绝连接，避免影响整个服务
+func (s *Server) admit(info ConnInfo) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewStatusError(StatusInternalServerError, fmt.Sprint(r))
+		}
+	}()
+
+	return s.options.acceptHandler(info)
+}
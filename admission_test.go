@@ -0,0 +1,84 @@
+package linker_test
+
+import (
+	"hash/crc32"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+// firstFrame 使用新的连接发送第一个数据包，返回服务端响应的数据包
+func firstFrame(t *testing.T, address, header string) linker.Packet {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	p, err := linker.NewPacket(crc32.ChecksumIEEE([]byte("/ping")), 1, []byte(header), []byte(`"ping"`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Write(p.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	rp, err := linker.ReadPacket(conn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rp
+}
+
+func TestAccept(t *testing.T) {
+	router := linker.NewRouter()
+	router.Route("/ping", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Success("pong")
+	}))
+
+	_, address := servertest.Start(t, router, linker.WithOnAccept(func(info linker.ConnInfo) error {
+		switch info.GetRequestProperty("token") {
+		case "secret":
+			return nil
+		case "panic":
+			panic("accept panic")
+		case "inject":
+			return linker.NewStatusError(linker.StatusUnauthorized, "invalid;code=200;token=x")
+		default:
+			return linker.NewStatusError(linker.StatusUnauthorized, "invalid token")
+		}
+	}))
+
+	cases := []struct {
+		header, code string
+	}{
+		{"token=secret;", ""},
+		{"token=bad;", "code=401;"},
+		{"token;", "code=401;"},
+		{"token=panic;", "code=500;"},
+		{"token=inject;", "code=401;message=invalid,code:200,token:x;"}, // 错误信息不能注入其他属性
+		{"token=secret;", ""},
+	}
+
+	for _, c := range cases {
+		rp := firstFrame(t, address, c.header)
+		if c.code == "" {
+			if string(rp.Body) != `"pong"` {
+				t.Fatalf("%s: unexpected response: %s %s", c.header, rp.Header, rp.Body)
+			}
+
+			continue
+		}
+
+		if !strings.Contains(string(rp.Header), c.code) {
+			t.Fatalf("%s: unexpected header: %s", c.header, rp.Header)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package graphql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -32,14 +33,11 @@ var queryType = graphql.NewObject(graphql.ObjectConfig{
 					return nil, err
 				}
 
-				var (
-					b           []byte
-					errCallback error
-				)
-
+				// 连接由所有请求共享，请求头只对本次请求生效，不能设置到连接上
 				ctx := p.Context.Value("ctx").(*gin.Context)
+				properties := make(map[string]string, len(ctx.Request.Header))
 				for k, v := range ctx.Request.Header {
-					session.SetRequestProperty(k, strings.Join(v, ","))
+					properties[k] = strings.Join(v, ",")
 				}
 
 				coder, err := codec.NewCoder(session.GetContentType())
@@ -55,31 +53,45 @@ var queryType = graphql.NewObject(graphql.ObjectConfig{
 					}
 				}
 
-				to, _ := context.WithTimeout(context.Background(), ctx.GetDuration("timeout"))
-				err = session.SyncSendWithTimeout(to, method.(string), body, client.RequestStatusCallback{
-					Success: func(header, body []byte) {
-						for _, v := range strings.Split(string(header), ";") {
-							if len(v) > 0 {
-								ss := strings.Split(v, "=")
-								if len(ss) > 1 {
-									ctx.Writer.Header().Set(ss[0], ss[1])
-								}
-							}
-						}
+				to, cancel := context.WithTimeout(context.Background(), ctx.GetDuration("timeout"))
+				defer cancel()
+
+				var (
+					header, b []byte
+					result    = make(chan error, 1)
+				)
 
-						b = body
+				abort, err := session.AsyncSendWithProperties(method.(string), properties, body, client.RequestStatusCallback{
+					Success: func(h, body []byte) {
+						header, b = h, body
+						result <- nil
 					},
 					Error: func(code int, message string) {
-						errCallback = errors.New(message)
+						result <- errors.New(message)
 					},
 				})
+				if err != nil {
+					return nil, err
+				}
+
+				select {
+				case err = <-result:
+				case <-to.Done():
+					abort()
+					err = fmt.Errorf("%s:%w", method.(string), to.Err())
+				}
 
 				if err != nil {
 					return nil, err
 				}
 
-				if errCallback != nil {
-					return nil, errCallback
+				for _, v := range strings.Split(string(header), ";") {
+					if len(v) > 0 {
+						ss := strings.Split(v, "=")
+						if len(ss) > 1 {
+							ctx.Writer.Header().Set(ss[0], ss[1])
+						}
+					}
 				}
 
 				return string(b), nil
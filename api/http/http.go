@@ -53,46 +53,55 @@ func (ha *httpAPI) Dial(network, address string) error {
 			return
 		}
 
-		var (
-			b           []byte
-			errCallback error
-		)
-
-		// 重置链接内保存的RequestProperty，避免影响到新过来的链接
-		session.Reset()
+		// 连接由所有请求共享，请求头只对本次请求生效，不能设置到连接上
+		properties := make(map[string]string, len(ctx.Request.Header))
 		for k, v := range ctx.Request.Header {
-			session.SetRequestProperty(k, strings.Join(v, ","))
+			properties[k] = strings.Join(v, ",")
 		}
 
-		to, _ := context.WithTimeout(context.Background(), ha.options.timeout)
-		err = session.SyncSendWithTimeout(to, req.Method, req.Param, client.RequestStatusCallback{
-			Success: func(header, body []byte) {
-				for _, v := range strings.Split(string(header), ";") {
-					if len(v) > 0 {
-						ss := strings.Split(v, "=")
-						if len(ss) > 1 {
-							ctx.Writer.Header().Set(ss[0], ss[1])
-						}
-					}
-				}
+		to, cancel := context.WithTimeout(context.Background(), ha.options.timeout)
+		defer cancel()
 
-				b = body
+		var (
+			header, b []byte
+			result    = make(chan error, 1)
+		)
+
+		abort, err := session.AsyncSendWithProperties(req.Method, properties, req.Param, client.RequestStatusCallback{
+			Success: func(h, body []byte) {
+				header, b = h, body
+				result <- nil
 			},
 			Error: func(code int, message string) {
-				errCallback = errors.New(message)
+				result <- errors.New(message)
 			},
 		})
-
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"msg": err.Error()})
 			return
 		}
 
-		if errCallback != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"msg": errCallback.Error()})
+		select {
+		case err = <-result:
+		case <-to.Done():
+			abort()
+			err = fmt.Errorf("%s:%w", req.Method, to.Err())
+		}
+
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"msg": err.Error()})
 			return
 		}
 
+		for _, v := range strings.Split(string(header), ";") {
+			if len(v) > 0 {
+				ss := strings.Split(v, "=")
+				if len(ss) > 1 {
+					ctx.Writer.Header().Set(ss[0], ss[1])
+				}
+			}
+		}
+
 		ctx.Data(http.StatusOK, session.GetContentType(), b)
 
 		return
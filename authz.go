@@ -0,0 +1,147 @@
+package linker
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+)
+
+// 认证中间件保存身份信息使用的key
+const ClaimsKey = "auth_claims"
+
+type (
+	// Claims 认证通过后得到的身份信息
+	Claims map[string]interface{}
+
+	// Policy 路由的访问策略，需要满足所有scope并且拥有任意一个role
+	Policy struct {
+		Scopes []string
+		Roles  []string
+	}
+)
+
+var _ Middleware = new(Policy)
+
+// RequireScopes 注册路由时声明需要的scope
+func RequireScopes(scopes ...string) *Policy {
+	return &Policy{Scopes: scopes}
+}
+
+// RequireRoles 注册路由时声明需要的role，拥有其中任意一个即可
+func RequireRoles(roles ...string) *Policy {
+	return &Policy{Roles: roles}
+}
+
+// Scopes 获取身份信息中的scope，支持空格分隔的scope字符串和scopes数组
+func (c Claims) Scopes() []string {
+	if v, ok := c["scope"].(string); ok {
+		return strings.Fields(v)
+	}
+
+	return c.strings("scopes")
+}
+
+// Roles 获取身份信息中的role，支持role字符串和roles数组
+func (c Claims) Roles() []string {
+	if v, ok := c["role"].(string); ok {
+		return []string{v}
+	}
+
+	return c.strings("roles")
+}
+
+// ExpiresAt 获取身份信息中exp表示的过期时间，没有exp时返回零值
+func (c Claims) ExpiresAt() time.Time {
+	var exp float64
+	switch v := c["exp"].(type) {
+	case float64:
+		exp = v
+	case int64:
+		exp = float64(v)
+	case int:
+		exp = float64(v)
+	case json.Number:
+		exp, _ = v.Float64()
+	}
+
+	if exp <= 0 {
+		return time.Time{}
+	}
+
+	sec, frac := math.Modf(exp)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+func (c Claims) strings(key string) []string {
+	switch v := c[key].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+
+		return list
+	}
+
+	return nil
+}
+
+// Handle 没有身份信息时返回StatusUnauthorized，不满足策略时返回StatusForbidden
+func (p *Policy) Handle(ctx Context) Context {
+	claims, ok := ctx.Get(ClaimsKey).(Claims)
+	if !ok {
+		ctx.Error(StatusUnauthorized, StatusText(StatusUnauthorized))
+	}
+
+	if !p.Allow(claims) {
+		ctx.Error(StatusForbidden, StatusText(StatusForbidden))
+	}
+
+	return ctx
+}
+
+// Allow 身份信息是否满足访问策略
+func (p *Policy) Allow(claims Claims) bool {
+	scopes := make(map[string]struct{})
+	for _, v := range claims.Scopes() {
+		scopes[v] = struct{}{}
+	}
+
+	for _, v := range p.Scopes {
+		if _, ok := scopes[v]; !ok {
+			return false
+		}
+	}
+
+	if len(p.Roles) == 0 {
+		return true
+	}
+
+	for _, have := range claims.Roles() {
+		for _, want := range p.Roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p *Policy) String() string {
+	var list []string
+	if len(p.Scopes) > 0 {
+		list = append(list, "scopes="+strings.Join(p.Scopes, ","))
+	}
+
+	if len(p.Roles) > 0 {
+		list = append(list, "roles="+strings.Join(p.Roles, ","))
+	}
+
+	return strings.Join(list, " ")
+}
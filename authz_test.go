@@ -0,0 +1,75 @@
+package linker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/export"
+	"github.com/wpajqz/linker/codec"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+// claimsMiddleware 使用请求属性中的scope和role作为身份信息，都为空时不保存身份信息
+type claimsMiddleware struct{}
+
+func (claimsMiddleware) Handle(ctx linker.Context) linker.Context {
+	scope, role := ctx.GetRequestProperty("scope"), ctx.GetRequestProperty("role")
+	if scope != "" || role != "" {
+		ctx.Set(linker.ClaimsKey, linker.Claims{"scope": scope, "role": role})
+	}
+
+	return ctx
+}
+
+func TestPolicy(t *testing.T) {
+	router := linker.NewRouter()
+	router.Use(claimsMiddleware{})
+	router.Route("/scoped", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Success(nil)
+	}), linker.RequireScopes("read", "write"))
+	router.Route("/roled", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Success(nil)
+	}), linker.RequireRoles("admin", "ops"))
+
+	_, address := servertest.Start(t, router)
+
+	c, err := export.NewClient(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.SetContentType(codec.JSON)
+
+	cases := []struct {
+		operator, scope, role string
+		code                  int
+	}{
+		{"/scoped", "", "", linker.StatusUnauthorized},
+		{"/scoped", "read", "", linker.StatusForbidden},
+		{"/scoped", "read write", "", 0},
+		{"/roled", "", "", linker.StatusUnauthorized},
+		{"/roled", "read", "user", linker.StatusForbidden},
+		{"/roled", "", "ops", 0},
+	}
+
+	for _, v := range cases {
+		c.SetRequestProperty("scope", v.scope)
+		c.SetRequestProperty("role", v.role)
+
+		result := make(chan int, 1)
+		if err := c.SyncSend(v.operator, nil, resultCallback{result}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case code := <-result:
+			if code != v.code {
+				t.Fatalf("%s scope=%q role=%q: unexpected code %d", v.operator, v.scope, v.role, code)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout waiting for result")
+		}
+	}
+}
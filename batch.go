@@ -0,0 +1,195 @@
+package linker
+
+import (
+	"bytes"
+	"context"
+	"hash/crc32"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wpajqz/linker/codec"
+)
+
+// 批量请求的子请求按照顺序逐个处理，默认并行处理
+const BatchOrderedProperty = "batch-ordered"
+
+var _ Context = new(contextBatch)
+
+type (
+	// BatchRequest 批量请求中的子请求，Body为按照内容类型编码后的参数
+	BatchRequest struct {
+		Operator string `json:"operator"`
+		Header   string `json:"header,omitempty"`
+		Body     []byte `json:"body,omitempty"`
+	}
+
+	// BatchResult 子请求的处理结果，Code为StatusOK时Body为编码后的响应内容
+	BatchResult struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+		Header  string `json:"header,omitempty"`
+		Body    []byte `json:"body,omitempty"`
+	}
+
+	// contextBatch 子请求的上下文，响应写入result而不是连接
+	contextBatch struct {
+		common
+		parent Context
+		result *BatchResult
+	}
+)
+
+// 子请求继承批量请求的属性和连接信息，子请求自己的属性优先
+func newContextBatch(parent Context, r BatchRequest, result *BatchResult) *contextBatch {
+	c := &contextBatch{parent: parent, result: result}
+
+	if b, ok := parent.(interface{ base() *common }); ok {
+		p := b.base()
+		c.common = common{
+			options:    p.options,
+			connection: p.connection,
+			cancelCtx:  p.cancelCtx,
+			sequence:   p.sequence,
+			Context:    p.Context,
+		}
+		c.Request.Header = append([]byte(nil), p.Request.Header...)
+
+		// 幂等键只对批量请求本身生效，子请求需要自己携带
+		if key := c.GetRequestProperty(IdempotencyKeyProperty); key != "" {
+			c.Request.Header = bytes.ReplaceAll(c.Request.Header, []byte(IdempotencyKeyProperty+"="+key+";"), nil)
+		}
+	}
+
+	if c.Context == nil {
+		c.Context = context.Background()
+	}
+
+	c.operateType = crc32.ChecksumIEEE([]byte(r.Operator))
+	c.body = r.Body
+	c.Request.Body = r.Body
+
+	for _, property := range strings.Split(r.Header, ";") {
+		kv := strings.SplitN(property, "=", 2)
+		if len(kv) == 2 && kv[0] != "" {
+			c.SetRequestProperty(kv[0], kv[1])
+		}
+	}
+
+	return c
+}
+
+func (dc *common) base() *common {
+	return dc
+}
+
+// 子请求成功，记录编码后的响应内容
+func (c *contextBatch) Success(body interface{}) {
+	r, err := codec.NewCoder(c.options.contentType)
+	if err != nil {
+		panic(err)
+	}
+
+	data, err := r.Encoder(body)
+	if err != nil {
+		panic(err)
+	}
+
+	*c.result = BatchResult{Code: StatusOK, Header: string(c.Response.Header), Body: data}
+
+	runtime.Goexit()
+}
+
+// 子请求失败，记录状态码和错误信息
+func (c *contextBatch) Error(code int, message string) {
+	c.SetResponseProperty("code", strconv.Itoa(code))
+	c.SetResponseProperty("message", message)
+
+	*c.result = BatchResult{Code: code, Message: message, Header: string(c.Response.Header)}
+
+	runtime.Goexit()
+}
+
+// 主动推送的数据直接通过批量请求所在的连接发送
+func (c *contextBatch) Write(operator string, body []byte) (int, error) {
+	return c.parent.Write(operator, body)
+}
+
+func (c *contextBatch) LocalAddr() string {
+	return c.parent.LocalAddr()
+}
+
+func (c *contextBatch) RemoteAddr() string {
+	return c.parent.RemoteAddr()
+}
+
+// serveBatch 把子请求分别交给路由和中间件处理，按照子请求的顺序返回结果，
+// 并行处理的子请求和普通请求一样占用连接和全局的并发名额，没有空闲名额时在批量请求自己的名额内依次处理
+func (s *Server) serveBatch(ctx Context) {
+	var requests []BatchRequest
+	if err := ctx.ParseParam(&requests); err != nil {
+		ctx.Error(StatusBadRequest, err.Error())
+	}
+
+	if s.options.maxBatchSize > 0 && len(requests) > s.options.maxBatchSize {
+		ctx.Error(StatusRequestEntityTooLarge, "too many requests in batch: "+strconv.Itoa(len(requests)))
+	}
+
+	ordered := ctx.GetRequestProperty(BatchOrderedProperty) == "1"
+	results := make([]BatchResult, len(requests))
+
+	var conn limiter
+	if c := ctx.Connection(); c != nil {
+		conn = c.limiter
+	}
+
+	var wg sync.WaitGroup
+	for i, r := range requests {
+		sub := newContextBatch(ctx, r, &results[i])
+
+		// 内部请求类型只能由连接直接发送，不能嵌套在批量请求中
+		if sub.operateType <= OperatorMax {
+			results[i] = BatchResult{Code: StatusBadRequest, Message: "unavailable operator in batch: " + r.Operator}
+			continue
+		}
+
+		parallel := !ordered && s.acquireSub(conn)
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				if parallel {
+					s.workers.release()
+					conn.release()
+				}
+
+				wg.Done()
+			}()
+
+			s.serve(sub, Packet{Operator: sub.operateType, Sequence: sub.sequence, Header: sub.Request.Header, Body: sub.body})
+		}()
+
+		if !parallel {
+			wg.Wait()
+		}
+	}
+
+	wg.Wait()
+
+	ctx.Success(results)
+}
+
+// acquireSub 不等待地为并行处理的子请求获取连接和全局的并发名额
+func (s *Server) acquireSub(conn limiter) bool {
+	if !conn.acquire(false) {
+		return false
+	}
+
+	if !s.workers.acquire(false) {
+		conn.release()
+		return false
+	}
+
+	return true
+}
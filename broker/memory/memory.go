@@ -7,14 +7,9 @@ import (
 	"github.com/wpajqz/linker/broker/memory/pubsub"
 )
 
-type (
-	memoryBroker struct {
-		ps sync.Map
-		pf sync.Map
-	}
-
-	topicMap map[string]func([]byte)
-)
+type memoryBroker struct {
+	ps sync.Map
+}
 
 func (mb *memoryBroker) Publish(topic string, message interface{}) error {
 	mb.ps.Range(func(key, value interface{}) bool {
@@ -33,19 +28,11 @@ func (mb *memoryBroker) Publish(topic string, message interface{}) error {
 func (mb *memoryBroker) Subscribe(nodeID, topic string, process func([]byte)) error {
 	actual, _ := mb.ps.LoadOrStore(nodeID, pubsub.New())
 	if av, ok := actual.(*pubsub.PubSub); ok {
-		actual, _ := mb.pf.LoadOrStore(nodeID, make(topicMap))
-		tm := actual.(topicMap)
-		if tm[topic] == nil {
-			tm[topic] = process
-		}
-
-		err := av.Subscribe(topic, func(i interface{}) {
+		return av.Subscribe(topic, func(i interface{}) {
 			if msg, ok := i.([]byte); ok {
-				tm[topic](msg)
+				process(msg)
 			}
 		})
-
-		return err
 	}
 
 	return nil
@@ -62,7 +49,10 @@ func (mb *memoryBroker) UnSubscribe(nodeID, topic string) error {
 }
 
 func (mb *memoryBroker) UnSubscribeAll(nodeID string) error {
-	mb.ps.Delete(nodeID)
+	if v, ok := mb.ps.Load(nodeID); ok {
+		mb.ps.Delete(nodeID)
+		v.(*pubsub.PubSub).Close()
+	}
 
 	return nil
 }
@@ -4,32 +4,44 @@ import "sync"
 
 type (
 	PubSub struct {
-		sync.Map
+		mu     sync.RWMutex
+		topics map[string]topicChain
 	}
 
 	topicChain chan interface{}
 )
 
 func New() *PubSub {
-	return &PubSub{}
+	return &PubSub{topics: make(map[string]topicChain)}
 }
 
+// 同一个topic只保留第一次订阅的处理函数
 func (ps *PubSub) Subscribe(topic string, process func(interface{})) error {
-	actual, _ := ps.LoadOrStore(topic, make(topicChain, 1000))
-	if ch, ok := actual.(topicChain); ok {
-		go func(chain topicChain) {
-			for msg := range ch {
-				go process(msg)
-			}
-		}(ch)
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.topics[topic]; ok {
+		return nil
 	}
 
+	ch := make(topicChain, 1000)
+	ps.topics[topic] = ch
+
+	go func(chain topicChain) {
+		for msg := range chain {
+			go process(msg)
+		}
+	}(ch)
+
 	return nil
 }
 
+// 没有订阅者的topic直接丢弃消息
 func (ps *PubSub) Publish(topic string, message interface{}) error {
-	actual, _ := ps.LoadOrStore(topic, make(topicChain, 1000))
-	if ch, ok := actual.(topicChain); ok {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	if ch, ok := ps.topics[topic]; ok {
 		ch <- message
 	}
 
@@ -37,5 +49,21 @@ func (ps *PubSub) Publish(topic string, message interface{}) error {
 }
 
 func (ps *PubSub) UnSubscribe(topic string) {
-	ps.Delete(topic)
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ch, ok := ps.topics[topic]; ok {
+		close(ch)
+		delete(ps.topics, topic)
+	}
+}
+
+func (ps *PubSub) Close() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for topic, ch := range ps.topics {
+		close(ch)
+		delete(ps.topics, topic)
+	}
 }
@@ -0,0 +1,26 @@
+package balancer
+
+import "errors"
+
+// error
+var ErrorNoEndpoint = errors.New("balancer: no endpoint available")
+
+// Endpoint 可以选择的服务端地址
+type Endpoint struct {
+	Address     string
+	Weight      int // 权重，小于等于0时按1处理
+	Outstanding int // 当前正在处理的请求数
+}
+
+// Balancer 负载均衡策略，properties为本次请求的请求属性
+type Balancer interface {
+	Pick(endpoints []Endpoint, properties map[string]string) (Endpoint, error)
+}
+
+func weight(e Endpoint) int {
+	if e.Weight <= 0 {
+		return 1
+	}
+
+	return e.Weight
+}
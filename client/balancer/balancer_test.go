@@ -0,0 +1,84 @@
+package balancer
+
+import "testing"
+
+func endpoints() []Endpoint {
+	return []Endpoint{
+		{Address: "a", Weight: 5},
+		{Address: "b", Weight: 1},
+		{Address: "c", Weight: 1},
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	b := NewWeighted()
+
+	count := make(map[string]int)
+	for i := 0; i < 70; i++ {
+		e, err := b.Pick(endpoints(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count[e.Address]++
+	}
+
+	if count["a"] != 50 || count["b"] != 10 || count["c"] != 10 {
+		t.Fatalf("unexpected distribution: %v", count)
+	}
+}
+
+func TestLeastOutstanding(t *testing.T) {
+	list := endpoints()
+	list[0].Outstanding, list[1].Outstanding, list[2].Outstanding = 3, 1, 2
+
+	for _, b := range []Balancer{NewLeastOutstanding(), NewP2C()} {
+		for i := 0; i < 20; i++ {
+			e, err := b.Pick(list, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// p2c不会选中请求数最多的地址
+			if e.Address == "a" {
+				t.Fatalf("%T picked the busiest endpoint", b)
+			}
+		}
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	b := NewConsistentHash("uid", 0)
+
+	first, err := b.Pick(endpoints(), map[string]string{"uid": "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		e, _ := b.Pick(endpoints(), map[string]string{"uid": "42"})
+		if e.Address != first.Address {
+			t.Fatalf("unstable pick: %s != %s", e.Address, first.Address)
+		}
+	}
+
+	// 移除其他地址不影响已经映射的请求
+	var list []Endpoint
+	for _, e := range endpoints() {
+		if e.Address == first.Address || len(list) == 0 {
+			list = append(list, e)
+		}
+	}
+
+	if e, _ := b.Pick(list, map[string]string{"uid": "42"}); e.Address != first.Address {
+		t.Fatalf("remapped after removing other endpoints: %s", e.Address)
+	}
+}
+
+func TestNoEndpoint(t *testing.T) {
+	for _, b := range []Balancer{NewRoundRobin(), NewWeighted(), NewLeastOutstanding(), NewP2C(), NewConsistentHash("uid", 10)} {
+		if _, err := b.Pick(nil, nil); err != ErrorNoEndpoint {
+			t.Fatalf("%T: unexpected error %v", b, err)
+		}
+	}
+}
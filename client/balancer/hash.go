@@ -0,0 +1,86 @@
+package balancer
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type consistentHash struct {
+	property string
+	replicas int
+	fallback Balancer
+
+	mu   sync.Mutex
+	key  string
+	ring []uint32
+	node map[uint32]string
+}
+
+// NewConsistentHash 根据请求属性property的值进行一致性哈希，相同的值总是选择同一个地址，
+// 地址变化时只影响少量的请求。请求没有携带该属性时轮询选择
+func NewConsistentHash(property string, replicas int) Balancer {
+	if replicas <= 0 {
+		replicas = 100
+	}
+
+	return &consistentHash{property: property, replicas: replicas, fallback: NewRoundRobin()}
+}
+
+func (b *consistentHash) Pick(endpoints []Endpoint, properties map[string]string) (Endpoint, error) {
+	if len(endpoints) == 0 {
+		return Endpoint{}, ErrorNoEndpoint
+	}
+
+	value, ok := properties[b.property]
+	if !ok || value == "" {
+		return b.fallback.Pick(endpoints, properties)
+	}
+
+	address := b.lookup(endpoints, crc32.ChecksumIEEE([]byte(value)))
+	for _, e := range endpoints {
+		if e.Address == address {
+			return e, nil
+		}
+	}
+
+	return Endpoint{}, ErrorNoEndpoint
+}
+
+// lookup 在哈希环上顺时针查找第一个节点，地址列表变化时重建哈希环
+func (b *consistentHash) lookup(endpoints []Endpoint, hash uint32) string {
+	addresses := make([]string, 0, len(endpoints))
+	for _, e := range endpoints {
+		addresses = append(addresses, e.Address)
+	}
+	sort.Strings(addresses)
+	key := strings.Join(addresses, ",")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if key != b.key {
+		b.key = key
+		b.ring = b.ring[:0]
+		b.node = make(map[uint32]string, len(addresses)*b.replicas)
+
+		for _, address := range addresses {
+			for i := 0; i < b.replicas; i++ {
+				h := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + address))
+				b.ring = append(b.ring, h)
+				b.node[h] = address
+			}
+		}
+
+		sort.Slice(b.ring, func(i, j int) bool { return b.ring[i] < b.ring[j] })
+	}
+
+	i := sort.Search(len(b.ring), func(i int) bool { return b.ring[i] >= hash })
+	if i == len(b.ring) {
+		i = 0
+	}
+
+	return b.node[b.ring[i]]
+}
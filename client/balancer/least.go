@@ -0,0 +1,75 @@
+package balancer
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type leastOutstanding struct{}
+
+// NewLeastOutstanding 选择正在处理的请求数最少的地址
+func NewLeastOutstanding() Balancer {
+	return leastOutstanding{}
+}
+
+func (leastOutstanding) Pick(endpoints []Endpoint, properties map[string]string) (Endpoint, error) {
+	if len(endpoints) == 0 {
+		return Endpoint{}, ErrorNoEndpoint
+	}
+
+	// 从随机位置开始遍历，避免请求数相同时总是选中第一个地址
+	start := random.Intn(len(endpoints))
+	best := endpoints[start]
+	for i := 1; i < len(endpoints); i++ {
+		e := endpoints[(start+i)%len(endpoints)]
+		if e.Outstanding < best.Outstanding {
+			best = e
+		}
+	}
+
+	return best, nil
+}
+
+type p2c struct{}
+
+// NewP2C 随机选择两个地址，使用正在处理的请求数较少的一个
+func NewP2C() Balancer {
+	return p2c{}
+}
+
+func (p2c) Pick(endpoints []Endpoint, properties map[string]string) (Endpoint, error) {
+	switch len(endpoints) {
+	case 0:
+		return Endpoint{}, ErrorNoEndpoint
+	case 1:
+		return endpoints[0], nil
+	}
+
+	i := random.Intn(len(endpoints))
+	j := random.Intn(len(endpoints) - 1)
+	if j >= i {
+		j++
+	}
+
+	if endpoints[j].Outstanding < endpoints[i].Outstanding {
+		return endpoints[j], nil
+	}
+
+	return endpoints[i], nil
+}
+
+// random 并发安全的随机数
+var random = &lockedRand{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (lr *lockedRand) Intn(n int) int {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	return lr.r.Intn(n)
+}
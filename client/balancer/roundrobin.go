@@ -0,0 +1,22 @@
+package balancer
+
+import "sync/atomic"
+
+type roundRobin struct {
+	next uint32
+}
+
+// NewRoundRobin 轮询选择地址
+func NewRoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+func (b *roundRobin) Pick(endpoints []Endpoint, properties map[string]string) (Endpoint, error) {
+	if len(endpoints) == 0 {
+		return Endpoint{}, ErrorNoEndpoint
+	}
+
+	n := atomic.AddUint32(&b.next, 1)
+
+	return endpoints[int(n%uint32(len(endpoints)))], nil
+}
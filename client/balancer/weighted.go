@@ -0,0 +1,51 @@
+package balancer
+
+import "sync"
+
+type weighted struct {
+	mu      sync.Mutex
+	current map[string]int
+}
+
+// NewWeighted 平滑加权轮询，权重高的地址被选中的次数更多，并且分布均匀
+func NewWeighted() Balancer {
+	return &weighted{current: make(map[string]int)}
+}
+
+func (b *weighted) Pick(endpoints []Endpoint, properties map[string]string) (Endpoint, error) {
+	if len(endpoints) == 0 {
+		return Endpoint{}, ErrorNoEndpoint
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var (
+		total int
+		best  = -1
+	)
+
+	seen := make(map[string]struct{}, len(endpoints))
+	for i, e := range endpoints {
+		seen[e.Address] = struct{}{}
+
+		w := weight(e)
+		total += w
+		b.current[e.Address] += w
+
+		if best == -1 || b.current[e.Address] > b.current[endpoints[best].Address] {
+			best = i
+		}
+	}
+
+	// 清理已经不存在的地址
+	for address := range b.current {
+		if _, ok := seen[address]; !ok {
+			delete(b.current, address)
+		}
+	}
+
+	b.current[endpoints[best].Address] -= total
+
+	return endpoints[best], nil
+}
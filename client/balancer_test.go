@@ -0,0 +1,141 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/balancer"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+// startWhoamiServers 启动n个返回自身地址的服务
+func startWhoamiServers(t *testing.T, n int) []string {
+	addresses := make([]string, n)
+	for i := range addresses {
+		address := servertest.Address(t)
+
+		router := linker.NewRouter()
+		router.Route("/whoami", linker.HandlerFunc(func(ctx linker.Context) {
+			ctx.Success(address)
+		}))
+
+		s := linker.NewServer(linker.WithTCPEndpoint(linker.Endpoint{Address: address}))
+		s.BindRouter(router)
+		servertest.Run(t, s, address)
+
+		addresses[i] = address
+	}
+
+	return addresses
+}
+
+func whoami(t *testing.T, c *Client, opts ...SessionOption) string {
+	session, err := c.Session(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var address string
+	err = session.SyncSend("/whoami", nil, RequestStatusCallback{
+		Success: func(header, body []byte) {
+			_ = json.Unmarshal(body, &address)
+		},
+		Error: func(code int, message string) {
+			t.Errorf("request error: %d %s", code, message)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return address
+}
+
+func TestBalancerRoundRobin(t *testing.T) {
+	balancerAddresses := startWhoamiServers(t, 3)
+
+	c, err := NewClient(balancerAddresses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	count := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		count[whoami(t, c)]++
+	}
+
+	for _, address := range balancerAddresses {
+		if count[address] != 10 {
+			t.Fatalf("unexpected distribution: %v", count)
+		}
+	}
+}
+
+func TestBalancerWeighted(t *testing.T) {
+	balancerAddresses := startWhoamiServers(t, 3)
+
+	c, err := NewClient(balancerAddresses, Balancer(balancer.NewWeighted()), Weights(map[string]int{balancerAddresses[0]: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	count := make(map[string]int)
+	for i := 0; i < 50; i++ {
+		count[whoami(t, c)]++
+	}
+
+	if count[balancerAddresses[0]] != 30 || count[balancerAddresses[1]] != 10 || count[balancerAddresses[2]] != 10 {
+		t.Fatalf("unexpected distribution: %v", count)
+	}
+}
+
+func TestBalancerConsistentHash(t *testing.T) {
+	balancerAddresses := startWhoamiServers(t, 3)
+
+	c, err := NewClient(balancerAddresses, Balancer(balancer.NewConsistentHash("uid", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	servers := make(map[string]bool)
+	for _, uid := range []string{"1", "2", "3", "4", "5", "6", "7", "8"} {
+		first := whoami(t, c, RequestProperty("uid", uid))
+		servers[first] = true
+
+		for i := 0; i < 5; i++ {
+			if address := whoami(t, c, RequestProperty("uid", uid)); address != first {
+				t.Fatalf("uid %s moved from %s to %s", uid, first, address)
+			}
+		}
+	}
+
+	if len(servers) < 2 {
+		t.Fatalf("all keys mapped to one server: %v", servers)
+	}
+}
+
+func TestBalancerLeastOutstanding(t *testing.T) {
+	balancerAddresses := startWhoamiServers(t, 3)
+
+	for _, b := range []balancer.Balancer{balancer.NewLeastOutstanding(), balancer.NewP2C()} {
+		c, err := NewClient(balancerAddresses, Balancer(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count := make(map[string]int)
+		for i := 0; i < 30; i++ {
+			count[whoami(t, c)]++
+		}
+
+		c.Close()
+
+		if len(count) < 2 {
+			t.Fatalf("%T: requests not spread: %v", b, count)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/codec"
+)
+
+type (
+	// Batch 在一个数据帧中发送多个请求，服务端按照路由和中间件分别处理后一次性返回所有结果
+	Batch struct {
+		client  *Client
+		ordered bool
+		calls   []batchCall
+	}
+
+	batchCall struct {
+		operator   string
+		properties map[string]string
+		req, resp  interface{}
+	}
+)
+
+func NewBatch() *Batch {
+	return defaultClient.Batch()
+}
+
+// Batch 创建批量请求，批量请求不经过拦截器，ctx中通过WithProperty设置的属性对所有子请求生效
+func (c *Client) Batch() *Batch {
+	return &Batch{client: c}
+}
+
+// Add 添加子请求，resp为nil时忽略响应内容
+func (b *Batch) Add(operator string, req, resp interface{}) *Batch {
+	return b.AddWithProperties(operator, nil, req, resp)
+}
+
+// AddWithProperties 添加子请求，properties只对该子请求生效
+func (b *Batch) AddWithProperties(operator string, properties map[string]string, req, resp interface{}) *Batch {
+	b.calls = append(b.calls, batchCall{operator: operator, properties: properties, req: req, resp: resp})
+	return b
+}
+
+// InOrder 服务端按照添加顺序逐个处理子请求，默认并行处理
+func (b *Batch) InOrder() *Batch {
+	b.ordered = true
+	return b
+}
+
+// Do 发送批量请求，返回的errs与添加的子请求一一对应，子请求失败时为*StatusError，
+// err不为nil时表示整个批量请求失败
+func (b *Batch) Do(ctx context.Context) ([]error, error) {
+	if len(b.calls) == 0 {
+		return nil, nil
+	}
+
+	properties := Properties(ctx)
+	if b.ordered {
+		properties = Properties(WithProperty(ctx, linker.BatchOrderedProperty, "1"))
+	}
+
+	conn, err := b.client.pick(properties, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	session := conn.Client
+
+	coder, err := codec.NewCoder(session.GetContentType())
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]linker.BatchRequest, len(b.calls))
+	for i, call := range b.calls {
+		body, err := coder.Encoder(call.req)
+		if err != nil {
+			return nil, err
+		}
+
+		requests[i] = linker.BatchRequest{Operator: call.operator, Header: encodeProperties(call.properties), Body: body}
+	}
+
+	type outcome struct {
+		errs []error
+		err  error
+	}
+
+	result := make(chan outcome, 1)
+	cancel, err := session.AsyncSendBatch(properties, requests, RequestStatusCallback{
+		Success: func(header, body []byte) {
+			var results []linker.BatchResult
+			if err := coder.Decoder(body, &results); err != nil {
+				result <- outcome{err: err}
+				return
+			}
+
+			if len(results) != len(b.calls) {
+				result <- outcome{err: ErrorBatchMismatch}
+				return
+			}
+
+			errs := make([]error, len(results))
+			for i, r := range results {
+				switch {
+				case r.Code != linker.StatusOK:
+					errs[i] = &StatusError{Code: r.Code, Message: r.Message}
+				case b.calls[i].resp != nil:
+					errs[i] = coder.Decoder(r.Body, b.calls[i].resp)
+				}
+			}
+
+			result <- outcome{errs: errs}
+		},
+		Error: func(code int, message string) {
+			result <- outcome{err: &StatusError{Code: code, Message: message}}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case o := <-result:
+		return o.errs, o.err
+	case <-ctx.Done():
+		cancel()
+		return nil, ctx.Err()
+	}
+}
+
+// encodeProperties 把属性编码为请求头格式
+func encodeProperties(properties map[string]string) string {
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k + "=" + properties[k] + ";")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+type countMiddleware struct {
+	count *int64
+}
+
+func (m countMiddleware) Handle(ctx linker.Context) linker.Context {
+	atomic.AddInt64(m.count, 1)
+	return ctx
+}
+
+func TestBatch(t *testing.T) {
+	var (
+		count int64
+		mu    sync.Mutex
+		order []string
+	)
+
+	router := linker.NewRouter()
+	router.Use(countMiddleware{&count})
+	router.Route("/echo", linker.HandlerFunc(func(ctx linker.Context) {
+		var param string
+		if err := ctx.ParseParam(&param); err != nil {
+			ctx.Error(linker.StatusBadRequest, err.Error())
+		}
+
+		ctx.Success(param + ":" + ctx.GetRequestProperty("token") + ":" + ctx.GetRequestProperty("lang"))
+	}))
+	router.Route("/fail", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Error(linker.StatusForbidden, "forbidden")
+	}))
+	router.Route("/sleep", linker.HandlerFunc(func(ctx linker.Context) {
+		var d time.Duration
+		_ = ctx.ParseParam(&d)
+		time.Sleep(d)
+
+		mu.Lock()
+		order = append(order, d.String())
+		mu.Unlock()
+
+		ctx.Success(nil)
+	}))
+	_, address := servertest.Start(t, router, linker.MaxBatchSize(3))
+
+	c, err := NewClient([]string{address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var a, b string
+	errs, err := c.Batch().
+		Add("/echo", "a", &a).
+		AddWithProperties("/echo", map[string]string{"lang": "en"}, "b", &b).
+		Add("/fail", nil, nil).
+		Do(WithProperty(context.Background(), "token", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(errs) != 3 || errs[0] != nil || errs[1] != nil || a != "a:secret:" || b != "b:secret:en" {
+		t.Fatalf("unexpected result: %v %s %s", errs, a, b)
+	}
+
+	if se, ok := errs[2].(*StatusError); !ok || se.Code != linker.StatusForbidden {
+		t.Fatalf("unexpected error: %v", errs[2])
+	}
+
+	// 每个子请求都经过中间件
+	if atomic.LoadInt64(&count) != 3 {
+		t.Fatalf("unexpected middleware calls: %d", count)
+	}
+
+	// 按照顺序处理时，较慢的子请求先完成
+	errs, err = c.Batch().InOrder().
+		Add("/sleep", 50*time.Millisecond, nil).
+		Add("/sleep", time.Millisecond, nil).
+		Do(context.Background())
+	if err != nil || errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected error: %v %v", err, errs)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(order) != 2 || order[0] != "50ms" {
+		t.Fatalf("unexpected order: %v", order)
+	}
+
+	// 子请求数量超过限制时整个批量请求失败
+	_, err = c.Batch().Add("/fail", nil, nil).Add("/fail", nil, nil).Add("/fail", nil, nil).Add("/fail", nil, nil).Do(context.Background())
+	if se, ok := err.(*StatusError); !ok || se.Code != linker.StatusRequestEntityTooLarge {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBatchLimit(t *testing.T) {
+	cases := []struct {
+		option  linker.Option
+		batches int
+	}{
+		{linker.MaxConcurrentRequestsPerConn(2), 1},
+		{linker.WorkerPoolSize(2), 2}, // 多个批量请求同时处理时子请求也不超过全局的并发限制
+	}
+
+	for _, v := range cases {
+		var running, peak int64
+		router := linker.NewRouter()
+		router.Route("/key", linker.HandlerFunc(func(ctx linker.Context) {
+			n := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+
+			for {
+				p := atomic.LoadInt64(&peak)
+				if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+					break
+				}
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			ctx.Success(ctx.GetRequestProperty(linker.IdempotencyKeyProperty))
+		}))
+		_, address := servertest.Start(t, router, v.option)
+
+		c, err := NewClient([]string{address})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < v.batches; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				keys := make([]string, 4)
+				batch := c.Batch().AddWithProperties("/key", map[string]string{linker.IdempotencyKeyProperty: "sub"}, nil, &keys[0])
+				for i := 1; i < len(keys); i++ {
+					batch.Add("/key", nil, &keys[i])
+				}
+
+				errs, err := batch.Do(WithProperty(context.Background(), linker.IdempotencyKeyProperty, "batch"))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				for _, err := range errs {
+					if err != nil {
+						t.Error(err)
+						return
+					}
+				}
+
+				// 批量请求的幂等键不会被子请求继承
+				if keys[0] != "sub" || keys[1] != "" || keys[2] != "" || keys[3] != "" {
+					t.Errorf("unexpected keys: %v", keys)
+				}
+			}()
+		}
+
+		wg.Wait()
+		_ = c.Close()
+
+		// 同时处理的子请求数不超过连接和全局的并发限制
+		if p := atomic.LoadInt64(&peak); p > 2 {
+			t.Fatalf("unexpected parallelism: %d", p)
+		}
+	}
+}
+
+func TestBatchInternalOperator(t *testing.T) {
+	router := linker.NewRouter()
+	router.Route("/echo", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Success(nil)
+	}))
+	_, address := servertest.Start(t, router)
+
+	c, err := NewClient([]string{address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// "/17654117"的crc32小于OperatorMax，与内部请求类型冲突
+	errs, err := c.Batch().Add("/17654117", nil, nil).Add("/echo", nil, nil).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if se, ok := errs[0].(*StatusError); !ok || se.Code != linker.StatusBadRequest || errs[1] != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
@@ -0,0 +1,296 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/wpajqz/linker/client"
+)
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrorOpen 熔断器打开时请求直接失败
+var ErrorOpen = errors.New("circuit breaker is open")
+
+type (
+	Options struct {
+		window           time.Duration
+		buckets          int
+		minRequests      int
+		errorRate        float64
+		slowCall         time.Duration
+		slowRate         float64
+		openTimeout      time.Duration
+		halfOpenRequests int
+		perOperator      bool
+		isFailure        func(code int) bool
+		onStateChange    func(key string, from, to State)
+	}
+
+	Option func(o *Options)
+
+	// Breaker 按照地址或者地址加请求类型统计请求结果，错误率或者慢请求比例超过阈值时打开熔断，
+	// 打开一段时间后进入半开状态放行少量探测请求，探测成功后关闭熔断
+	Breaker struct {
+		options  Options
+		mu       sync.Mutex
+		circuits map[string]*circuit
+	}
+)
+
+// Window 统计窗口，默认10秒
+func Window(d time.Duration) Option {
+	return func(o *Options) {
+		o.window = d
+	}
+}
+
+// MinRequests 统计窗口内的请求数达到该值以后才会计算错误率，默认20
+func MinRequests(n int) Option {
+	return func(o *Options) {
+		o.minRequests = n
+	}
+}
+
+// ErrorRate 错误率达到该值时打开熔断，默认0.5
+func ErrorRate(rate float64) Option {
+	return func(o *Options) {
+		o.errorRate = rate
+	}
+}
+
+// SlowCall 耗时超过d的请求为慢请求，慢请求比例达到rate时打开熔断，默认不统计慢请求
+func SlowCall(d time.Duration, rate float64) Option {
+	return func(o *Options) {
+		o.slowCall = d
+		o.slowRate = rate
+	}
+}
+
+// OpenTimeout 熔断打开后经过多久进入半开状态，默认5秒
+func OpenTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.openTimeout = d
+	}
+}
+
+// HalfOpenRequests 半开状态下放行的探测请求数，全部成功后关闭熔断，默认1
+func HalfOpenRequests(n int) Option {
+	return func(o *Options) {
+		o.halfOpenRequests = n
+	}
+}
+
+// PerOperator 按照地址加请求类型分别熔断，默认只按照地址熔断
+func PerOperator() Option {
+	return func(o *Options) {
+		o.perOperator = true
+	}
+}
+
+// IsFailure 判断响应状态码是否为失败，默认5xx为失败，0表示请求成功
+func IsFailure(fn func(code int) bool) Option {
+	return func(o *Options) {
+		o.isFailure = fn
+	}
+}
+
+// OnStateChange 熔断器状态变化时的回调，key为地址，按照请求类型熔断时为地址|请求类型
+func OnStateChange(fn func(key string, from, to State)) Option {
+	return func(o *Options) {
+		o.onStateChange = fn
+	}
+}
+
+func New(opts ...Option) *Breaker {
+	options := Options{
+		window:           10 * time.Second,
+		buckets:          10,
+		minRequests:      20,
+		errorRate:        0.5,
+		openTimeout:      5 * time.Second,
+		halfOpenRequests: 1,
+		isFailure: func(code int) bool {
+			return code >= 500
+		},
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.minRequests <= 0 {
+		options.minRequests = 1
+	}
+
+	if options.halfOpenRequests <= 0 {
+		options.halfOpenRequests = 1
+	}
+
+	return &Breaker{options: options, circuits: make(map[string]*circuit)}
+}
+
+// Allow 检查是否允许向address发送operator请求，熔断打开时返回ErrorOpen，
+// 允许时返回的done需要在请求结束时调用，code为0表示请求成功，为client.NoResult时不统计该请求
+func (b *Breaker) Allow(address, operator string) (func(code int), error) {
+	key := b.key(address, operator)
+	c := b.circuit(key)
+
+	c.mu.Lock()
+	now := time.Now()
+	from := c.state
+	if c.state == StateOpen && now.Sub(c.openedAt) >= b.options.openTimeout {
+		c.setState(StateHalfOpen, now)
+	}
+
+	switch c.state {
+	case StateOpen:
+		c.mu.Unlock()
+		return nil, ErrorOpen
+	case StateHalfOpen:
+		if c.probes >= b.options.halfOpenRequests {
+			c.mu.Unlock()
+			b.notify(key, from, c.state)
+			return nil, ErrorOpen
+		}
+
+		c.probes++
+	}
+
+	generation := c.generation
+	to := c.state
+	c.mu.Unlock()
+
+	b.notify(key, from, to)
+
+	var once sync.Once
+	return func(code int) {
+		once.Do(func() {
+			b.done(key, c, generation, now, code)
+		})
+	}, nil
+}
+
+// UnaryInterceptor 作为client的请求拦截器使用，按照每一次尝试实际发送的地址和请求类型统计请求结果，
+// 熔断打开的地址不会被负载均衡选中，全部地址都被熔断时请求直接返回ErrorOpen
+func (b *Breaker) UnaryInterceptor(ctx context.Context, operator string, req, resp interface{}, invoker client.Invoker) error {
+	return invoker(client.WithGuard(ctx, b), operator, req, resp)
+}
+
+// Available 地址是否可以接收请求，按照请求类型熔断时总是返回true
+func (b *Breaker) Available(address string) bool {
+	if b.options.perOperator {
+		return true
+	}
+
+	return b.State(address, "") != StateOpen
+}
+
+// State 获取熔断器当前状态
+func (b *Breaker) State(address, operator string) State {
+	b.mu.Lock()
+	c, ok := b.circuits[b.key(address, operator)]
+	b.mu.Unlock()
+
+	if !ok {
+		return StateClosed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == StateOpen && time.Since(c.openedAt) >= b.options.openTimeout {
+		return StateHalfOpen
+	}
+
+	return c.state
+}
+
+func (b *Breaker) key(address, operator string) string {
+	if b.options.perOperator && operator != "" {
+		return address + "|" + operator
+	}
+
+	return address
+}
+
+func (b *Breaker) circuit(key string) *circuit {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{buckets: make([]bucket, b.options.buckets)}
+		b.circuits[key] = c
+	}
+
+	return c
+}
+
+// done 记录请求结果，状态已经变化的请求结果不再统计，没有结果的请求只释放半开状态的探测名额
+func (b *Breaker) done(key string, c *circuit, generation int64, start time.Time, code int) {
+	if code == client.NoResult {
+		c.mu.Lock()
+		if generation == c.generation && c.state == StateHalfOpen {
+			c.probes--
+		}
+		c.mu.Unlock()
+
+		return
+	}
+
+	now := time.Now()
+	failure := b.options.isFailure(code)
+	slow := b.options.slowCall > 0 && now.Sub(start) >= b.options.slowCall
+
+	c.mu.Lock()
+	from := c.state
+	if generation == c.generation {
+		switch c.state {
+		case StateHalfOpen:
+			if failure || slow {
+				c.setState(StateOpen, now)
+			} else if c.successes++; c.successes >= b.options.halfOpenRequests {
+				c.setState(StateClosed, now)
+			}
+		case StateClosed:
+			total, failures, slows := c.record(now, b.options.window, failure, slow)
+			if total >= b.options.minRequests && (float64(failures)/float64(total) >= b.options.errorRate ||
+				b.options.slowCall > 0 && float64(slows)/float64(total) >= b.options.slowRate) {
+				c.setState(StateOpen, now)
+			}
+		}
+	}
+	to := c.state
+	c.mu.Unlock()
+
+	b.notify(key, from, to)
+}
+
+func (b *Breaker) notify(key string, from, to State) {
+	if from != to && b.options.onStateChange != nil {
+		b.options.onStateChange(key, from, to)
+	}
+}
@@ -0,0 +1,157 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker/client"
+)
+
+type transition struct {
+	key      string
+	from, to State
+}
+
+func call(t *testing.T, b *Breaker, address, operator string, code int) {
+	done, err := b.Allow(address, operator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done(code)
+}
+
+func TestBreaker(t *testing.T) {
+	var transitions []transition
+	b := New(
+		MinRequests(4),
+		ErrorRate(0.5),
+		OpenTimeout(50*time.Millisecond),
+		HalfOpenRequests(2),
+		OnStateChange(func(key string, from, to State) {
+			transitions = append(transitions, transition{key, from, to})
+		}),
+	)
+
+	call(t, b, "a", "/x", 0)
+	call(t, b, "a", "/x", 404)
+	call(t, b, "a", "/x", 500)
+	if b.State("a", "") != StateClosed {
+		t.Fatal("breaker should stay closed before min requests")
+	}
+
+	call(t, b, "a", "/y", 503)
+	if b.State("a", "") != StateOpen || b.Available("a") || !b.Available("b") {
+		t.Fatalf("unexpected state: %s", b.State("a", ""))
+	}
+
+	if _, err := b.Allow("a", "/x"); err != ErrorOpen {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	// 半开状态只放行指定数量的探测请求
+	d1, err := b.Allow("a", "/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d2, err := b.Allow("a", "/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.Allow("a", "/x"); err != ErrorOpen {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d1(0)
+	d2(500)
+	if b.State("a", "") != StateOpen {
+		t.Fatal("failed probe should reopen the breaker")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	call(t, b, "a", "/x", 0)
+	call(t, b, "a", "/x", 0)
+	if b.State("a", "") != StateClosed {
+		t.Fatal("successful probes should close the breaker")
+	}
+
+	expected := []transition{
+		{"a", StateClosed, StateOpen},
+		{"a", StateOpen, StateHalfOpen},
+		{"a", StateHalfOpen, StateOpen},
+		{"a", StateOpen, StateHalfOpen},
+		{"a", StateHalfOpen, StateClosed},
+	}
+
+	if len(transitions) != len(expected) {
+		t.Fatalf("unexpected transitions: %v", transitions)
+	}
+
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Fatalf("unexpected transitions: %v", transitions)
+		}
+	}
+}
+
+func TestSlowCallPerOperator(t *testing.T) {
+	b := New(MinRequests(2), SlowCall(20*time.Millisecond, 0.5), PerOperator())
+
+	for i := 0; i < 2; i++ {
+		done, err := b.Allow("a", "/slow")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(25 * time.Millisecond)
+		done(0)
+	}
+
+	if b.State("a", "/slow") != StateOpen {
+		t.Fatal("slow calls should open the breaker")
+	}
+
+	// 按照请求类型熔断时，同一个地址上的其他请求不受影响
+	if b.State("a", "/fast") != StateClosed || !b.Available("a") {
+		t.Fatal("other operators should not be affected")
+	}
+
+	call(t, b, "a", "/fast", 0)
+}
+
+func TestNoResult(t *testing.T) {
+	b := New(MinRequests(2), OpenTimeout(50*time.Millisecond))
+
+	// 没有结果的请求不参与统计
+	for i := 0; i < 4; i++ {
+		call(t, b, "a", "/x", client.NoResult)
+	}
+
+	call(t, b, "a", "/x", 500)
+	if b.State("a", "") != StateClosed {
+		t.Fatal("requests without result should not be counted")
+	}
+
+	call(t, b, "a", "/x", 500)
+	if b.State("a", "") != StateOpen {
+		t.Fatalf("unexpected state: %s", b.State("a", ""))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	// 被取消的探测请求释放探测名额，不会关闭熔断
+	call(t, b, "a", "/x", client.NoResult)
+	if b.State("a", "") != StateHalfOpen {
+		t.Fatalf("unexpected state: %s", b.State("a", ""))
+	}
+
+	call(t, b, "a", "/x", 0)
+	if b.State("a", "") != StateClosed {
+		t.Fatalf("unexpected state: %s", b.State("a", ""))
+	}
+}
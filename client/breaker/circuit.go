@@ -0,0 +1,74 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket 滑动窗口中的一个时间片
+type bucket struct {
+	index    int64
+	total    int
+	failures int
+	slows    int
+}
+
+// circuit 单个熔断器的状态以及统计数据，generation在每次状态变化时增加
+type circuit struct {
+	mu         sync.Mutex
+	state      State
+	generation int64
+	openedAt   time.Time
+	probes     int
+	successes  int
+	buckets    []bucket
+}
+
+func (c *circuit) setState(state State, now time.Time) {
+	c.state = state
+	c.generation++
+	c.probes = 0
+	c.successes = 0
+
+	switch state {
+	case StateOpen:
+		c.openedAt = now
+	case StateClosed:
+		for i := range c.buckets {
+			c.buckets[i] = bucket{}
+		}
+	}
+}
+
+// record 记录一次请求结果，返回窗口内的请求数、失败数和慢请求数
+func (c *circuit) record(now time.Time, window time.Duration, failure, slow bool) (total, failures, slows int) {
+	size := window / time.Duration(len(c.buckets))
+	if size <= 0 {
+		size = 1
+	}
+
+	index := now.UnixNano() / int64(size)
+	b := &c.buckets[index%int64(len(c.buckets))]
+	if b.index != index {
+		*b = bucket{index: index}
+	}
+
+	b.total++
+	if failure {
+		b.failures++
+	}
+
+	if slow {
+		b.slows++
+	}
+
+	for _, v := range c.buckets {
+		if index-v.index < int64(len(c.buckets)) {
+			total += v.total
+			failures += v.failures
+			slows += v.slows
+		}
+	}
+
+	return total, failures, slows
+}
@@ -0,0 +1,103 @@
+package breaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client"
+	"github.com/wpajqz/linker/client/breaker"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+func startServer(t *testing.T, code int) string {
+	router := linker.NewRouter()
+	router.Route("/whoami", linker.HandlerFunc(func(ctx linker.Context) {
+		if code != 0 {
+			ctx.Error(code, "degraded")
+		}
+
+		ctx.Success("ok")
+	}))
+
+	_, address := servertest.Start(t, router)
+
+	return address
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	bad := startServer(t, linker.StatusInternalServerError)
+
+	b := breaker.New(breaker.MinRequests(2), breaker.OpenTimeout(time.Minute))
+	c, err := client.NewClient([]string{bad, startServer(t, 0)}, client.WithUnaryInterceptor(b.UnaryInterceptor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	failures := 0
+	for i := 0; i < 10; i++ {
+		if err := c.Invoke(context.Background(), "/whoami", nil, nil); err != nil {
+			if _, ok := err.(*client.StatusError); !ok {
+				t.Fatal(err)
+			}
+
+			failures++
+		}
+	}
+
+	// 熔断打开以后请求不再发送到异常的地址
+	if failures != 2 || b.State(bad, "") != breaker.StateOpen {
+		t.Fatalf("unexpected failures: %d, state: %s", failures, b.State(bad, ""))
+	}
+
+	only, err := client.NewClient([]string{bad}, client.WithUnaryInterceptor(b.UnaryInterceptor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer only.Close()
+
+	if err := only.Invoke(context.Background(), "/whoami", nil, nil); err != breaker.ErrorOpen {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	router := linker.NewRouter()
+	router.Route("/hang", linker.HandlerFunc(func(ctx linker.Context) {
+		select {
+		case <-ctx.Ctx().Done():
+		case <-release:
+		}
+	}))
+	_, address := servertest.Start(t, router)
+	t.Cleanup(func() { close(release) })
+
+	b := breaker.New(breaker.MinRequests(2), breaker.OpenTimeout(time.Minute))
+	c, err := client.NewClient([]string{address}, client.WithUnaryInterceptor(b.UnaryInterceptor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// 服务端一直没有响应的请求超时以后计为失败
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := c.Invoke(ctx, "/hang", nil, nil)
+		cancel()
+
+		if err != context.DeadlineExceeded {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if b.State(address, "") != breaker.StateOpen {
+		t.Fatalf("unexpected state: %s", b.State(address, ""))
+	}
+
+	if err := c.Invoke(context.Background(), "/hang", nil, nil); err != breaker.ErrorOpen {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -2,9 +2,11 @@ package client
 
 type (
 	ReadyStateCallback struct {
-		Open  func()
-		Close func()
-		Error func(err error)
+		Open         func()
+		Close        func()
+		Error        func(err error)
+		Reconnecting func(attempt int)
+		Reconnected  func()
 	}
 
 	RequestStatusCallback struct {
@@ -33,6 +35,18 @@ func (r *ReadyStateCallback) OnError(err error) {
 	}
 }
 
+func (r *ReadyStateCallback) OnReconnecting(attempt int) {
+	if r.Reconnecting != nil {
+		r.Reconnecting(attempt)
+	}
+}
+
+func (r *ReadyStateCallback) OnReconnected() {
+	if r.Reconnected != nil {
+		r.Reconnected()
+	}
+}
+
 func (r RequestStatusCallback) OnStart() {
 	if r.Start != nil {
 		r.Start()
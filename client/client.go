@@ -2,11 +2,10 @@ package client
 
 import (
 	"context"
-	"fmt"
 	"time"
 
-	"github.com/silenceper/pool"
 	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/balancer"
 	"github.com/wpajqz/linker/client/export"
 	"github.com/wpajqz/linker/codec"
 )
@@ -18,74 +17,237 @@ var (
 
 type (
 	Client struct {
-		options          options
-		clientPool       pool.Pool
-		address          []string
-		availableAddress chan string
+		options options
+		pool    *pool
+		budget  hedgeBudget
 	}
+
+	sessionOptions struct {
+		properties map[string]string
+	}
+
+	SessionOption func(*sessionOptions)
 )
 
+// NewClient 创建客户端，使用WithResolver时地址由解析器提供，address可以为空
 func NewClient(address []string, opts ...Option) (*Client, error) {
 	options := options{
-		network:     defaultNetwork,
-		contentType: codec.JSON,
-		udpPayload:  4096,
-		dialTimeout: 3 * time.Second,
-		initialCap:  10,
-		maxCap:      30,
-		ext:         make(map[string]string),
+		network:              defaultNetwork,
+		contentType:          codec.JSON,
+		udpPayload:           4096,
+		dialTimeout:          3 * time.Second,
+		minConns:             1,
+		maxConns:             4,
+		maxStreams:           128,
+		maxHeartbeatFailures: 3,
+		heartbeatInterval:    60 * time.Second,
+		drainTimeout:         10 * time.Second,
+		balancer:             balancer.NewRoundRobin(),
+		ext:                  make(map[string]string),
 	}
 
 	for _, o := range opts {
 		o(&options)
 	}
 
-	defaultClient = &Client{
-		options:          options,
-		address:          address,
-		availableAddress: make(chan string, len(address)),
+	if options.maxConns < options.minConns {
+		options.maxConns = options.minConns
 	}
 
-	defaultClient.fillAddress(address)
+	if options.resolver == nil {
+		if len(address) == 0 {
+			return nil, ErrorNoAvailableAddress
+		}
+
+		options.resolver = NewStaticResolver(address...)
+	}
 
-	p, err := defaultClient.newExportPool()
+	p, err := newPool(options)
 	if err != nil {
 		return nil, err
 	}
 
-	defaultClient.clientPool = p
+	defaultClient = &Client{options: options, pool: p}
 
-	return defaultClient, err
+	return defaultClient, nil
 }
 
-func Session() (*export.Client, error) {
-	return defaultClient.Session()
+// RequestProperty 本次请求的请求属性，用于负载均衡策略选择地址，例如一致性哈希
+func RequestProperty(key, value string) SessionOption {
+	return func(o *sessionOptions) {
+		o.properties[key] = value
+	}
 }
 
-func (c *Client) Session() (*export.Client, error) {
-	v, err := c.clientPool.Get()
+func Session(opts ...SessionOption) (*export.Client, error) {
+	return defaultClient.Session(opts...)
+}
+
+func Invoke(ctx context.Context, operator string, req, resp interface{}) error {
+	return defaultClient.Invoke(ctx, operator, req, resp)
+}
+
+func Subscribe(ctx context.Context, topic string, handler export.Handler) error {
+	return defaultClient.Subscribe(ctx, topic, handler)
+}
+
+// Session 获取一个连接，连接由连接池管理，同一个连接可以被多个调用方同时使用，使用后不需要归还
+func (c *Client) Session(opts ...SessionOption) (*export.Client, error) {
+	o := sessionOptions{properties: make(map[string]string)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	conn, err := c.pick(o.properties, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer c.clientPool.Put(v)
 
-	return v.(*export.Client), nil
+	return conn.Client, nil
 }
 
-func (c *Client) fillAddress(address []string) {
-	for _, v := range address {
-		defaultClient.availableAddress <- v
+// pick 按照请求属性选择连接，ext中的属性作为默认值，g不为nil时不选择g中不可用的地址，尽量不选择exclude中的地址
+func (c *Client) pick(properties map[string]string, g Guard, exclude ...string) (*conn, error) {
+	merged := make(map[string]string, len(c.options.ext)+len(properties))
+	for k, v := range c.options.ext {
+		merged[k] = v
+	}
+
+	for k, v := range properties {
+		merged[k] = v
+	}
+
+	var available func(address string) bool
+	if g != nil {
+		available = g.Available
+	}
+
+	return c.pool.get(merged, available, exclude...)
+}
+
+// Invoke 经过拦截器发送请求，等待响应并解码到resp中，服务端返回错误时返回*StatusError，
+// 通过WithProperty设置的请求属性只对本次请求生效，重试在所有拦截器之后执行
+func (c *Client) Invoke(ctx context.Context, operator string, req, resp interface{}) error {
+	interceptors := c.options.unaryInterceptors
+	if len(c.options.retries) > 0 {
+		interceptors = append(interceptors[:len(interceptors):len(interceptors)], c.retry)
+	}
+
+	if len(c.options.hedges) > 0 {
+		interceptors = append(interceptors[:len(interceptors):len(interceptors)], c.hedge)
+	}
+
+	return chainUnaryInterceptors(interceptors, c.invoke)(ctx, operator, req, resp)
+}
+
+func (c *Client) invoke(ctx context.Context, operator string, req, resp interface{}) error {
+	properties := Properties(ctx)
+	g := guard(ctx)
+
+	conn, err := c.pick(properties, g, attempted(ctx).addresses()...)
+	if err != nil {
+		return err
+	}
+
+	attempted(ctx).add(conn.address)
+	session := conn.Client
+
+	done := func(code int) {}
+	if g != nil {
+		if done, err = g.Allow(conn.address, operator); err != nil {
+			return err
+		}
 	}
+
+	result := make(chan error, 1)
+	cancel, err := session.AsyncSendWithProperties(operator, properties, req, RequestStatusCallback{
+		Success: func(header, body []byte) {
+			if resp == nil {
+				result <- nil
+				return
+			}
+
+			coder, err := codec.NewCoder(session.GetContentType())
+			if err != nil {
+				result <- err
+				return
+			}
+
+			result <- coder.Decoder(body, resp)
+		},
+		Error: func(code int, message string) {
+			result <- &StatusError{Code: code, Message: message}
+		},
+	})
+	if err != nil {
+		// 连接不可用导致的发送失败可以重试并且计为失败，编码失败等其他错误重试也不会改变结果
+		if session.GetReadyState() != export.OPEN {
+			done(linker.StatusServiceUnavailable)
+			return &TransportError{Err: err}
+		}
+
+		done(NoResult)
+		return err
+	}
+
+	select {
+	case err := <-result:
+		done(statusCode(err))
+		return err
+	case <-ctx.Done():
+		cancel()
+
+		// 超时说明服务端没有及时响应，计为失败，调用方主动取消或者对冲请求中落后的请求不统计
+		if ctx.Err() == context.DeadlineExceeded {
+			done(linker.StatusGatewayTimeout)
+		} else {
+			done(NoResult)
+		}
+
+		return ctx.Err()
+	}
+}
+
+// Subscribe 经过拦截器订阅topic推送的消息，ctx结束时取消订阅
+func (c *Client) Subscribe(ctx context.Context, topic string, handler export.Handler) error {
+	return chainStreamInterceptors(c.options.streamInterceptors, c.subscribe)(ctx, topic, handler)
 }
 
-func (c *Client) getAddress() (string, error) {
-	ctx, _ := context.WithTimeout(context.Background(), c.options.dialTimeout)
-	for {
+func (c *Client) subscribe(ctx context.Context, topic string, handler export.Handler) error {
+	conn, err := c.pick(Properties(ctx), nil)
+	if err != nil {
+		return err
+	}
+
+	session := conn.Client
+
+	if err := session.AddMessageListener(topic, handler); err != nil {
+		return err
+	}
+
+	go func() {
 		select {
-		case addr := <-c.availableAddress:
-			return addr, nil
 		case <-ctx.Done():
-			return "", fmt.Errorf("brpc error: no address available %s\n", ctx.Err())
+			_ = session.RemoveMessageListener(topic)
+		case <-c.pool.done:
 		}
-	}
+	}()
+
+	return nil
+}
+
+// Stats 获取连接池状态
+func (c *Client) Stats() Stats {
+	return c.pool.stats("")
+}
+
+// AddressStats 获取指定地址的连接池状态
+func (c *Client) AddressStats(address string) Stats {
+	return c.pool.stats(address)
+}
+
+// Close 关闭连接池中的所有连接
+func (c *Client) Close() error {
+	c.pool.close()
+	return nil
 }
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"errors"
+	"strconv"
+)
+
+// error
+var (
+	ErrorNoAvailableAddress = errors.New("brpc error: no address available")
+	ErrorNoAvailableConn    = errors.New("brpc error: no connection available")
+	ErrorHeartbeatTimeout   = errors.New("brpc error: heartbeat timeout")
+	ErrorPoolClosed         = errors.New("brpc error: pool is closed")
+	ErrorBatchMismatch      = errors.New("brpc error: batch results mismatch")
+)
+
+// StatusError 服务端返回的错误状态
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return "brpc error: status " + strconv.Itoa(e.Code) + ", " + e.Message
+}
+
+// TransportError 请求没有发送到服务端，Err为原始错误
+type TransportError struct {
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// statusCode 获取请求结果的状态码，服务端没有返回错误状态时为0
+func statusCode(err error) int {
+	if e, ok := err.(*StatusError); ok {
+		return e.Code
+	}
+
+	return 0
+}
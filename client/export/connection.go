@@ -12,8 +12,8 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-// handleConnection 处理客户端连接
-func (c *Client) handleConnection(network string, conn net.Conn) {
+// handleConnection 处理客户端连接，reconnected表示由自动重连建立的连接
+func (c *Client) handleConnection(network string, conn net.Conn, reconnected bool) {
 	eg, ctx := errgroup.WithContext(context.Background())
 
 	eg.Go(func() error {
@@ -32,29 +32,64 @@ func (c *Client) handleConnection(network string, conn net.Conn) {
 	})
 
 	eg.Go(func() error {
-		return c.handleSendPackets(ctx, conn)
+		err := c.handleSendPackets(ctx, conn)
+		// 关闭连接使接收数据的routine退出
+		_ = conn.Close()
+		return err
 	})
 
-	// wait one second for receive and send routine loaded
-	time.Sleep(time.Second)
-	if c.readyStateCallback.OnOpen != nil {
-		go c.readyStateCallback.OnOpen()
+	if !reconnected && c.readyStateCallback != nil {
+		// wait one second for receive and send routine loaded
+		go func() {
+			time.Sleep(time.Second)
+			if c.GetReadyState() == OPEN {
+				c.readyStateCallback.OnOpen()
+			}
+		}()
 	}
 
 	err := eg.Wait()
-	if err != nil {
+	if err == nil {
+		return
+	}
+
+	if c.isClosed() {
+		c.rwMutex.Lock()
 		c.readyState = CLOSED
-		if err == io.EOF {
-			if c.readyStateCallback.OnClose != nil {
-				c.readyStateCallback.OnClose()
-			}
-		} else {
-			if c.readyStateCallback.OnError != nil {
-				c.readyStateCallback.OnError(err)
-			}
+		c.rwMutex.Unlock()
+
+		c.failPending(ErrorClientClosed, true)
+		if c.readyStateCallback != nil {
+			c.readyStateCallback.OnClose()
+		}
+
+		return
+	}
+
+	c.rwMutex.RLock()
+	reconnect := c.reconnect != nil
+	c.rwMutex.RUnlock()
+
+	if reconnect {
+		c.startReconnect()
+		return
+	}
+
+	c.rwMutex.Lock()
+	c.readyState = CLOSED
+	c.rwMutex.Unlock()
+
+	c.failPending(ErrorConnectionLost, true)
+	if err == io.EOF {
+		if c.readyStateCallback != nil {
+			c.readyStateCallback.OnClose()
+		}
+	} else {
+		if c.readyStateCallback != nil {
+			c.readyStateCallback.OnError(err)
 		}
-		_ = c.Close()
 	}
+	_ = c.Close()
 }
 
 // handleSendPackets 对发送的数据包进行处理
@@ -62,6 +97,7 @@ func (c *Client) handleSendPackets(ctx context.Context, conn net.Conn) error {
 	for {
 		select {
 		case p := <-c.packet:
+			c.markSent(p)
 			_, err := conn.Write(p.Bytes())
 			if err != nil {
 				return err
@@ -79,6 +115,20 @@ func (c *Client) handleSendPackets(ctx context.Context, conn net.Conn) error {
 	}
 }
 
+// handlePing 回复服务端主动发送的心跳
+func (c *Client) handlePing(receive linker.Packet) bool {
+	if receive.Operator != linker.OperatorHeartbeat || c.GetResponseProperty(linker.PingProperty) == "" {
+		return false
+	}
+
+	p, err := linker.NewPacket(linker.OperatorHeartbeat, receive.Sequence, c.request.Header, nil, c.pluginForPacketSender)
+	if err == nil {
+		c.packet <- p
+	}
+
+	return true
+}
+
 // handleReceivedUDPPackets 对接收到的数据包进行处理
 func (c *Client) handleReceivedUDPPackets(conn net.Conn) error {
 	udpConn := conn.(*net.UDPConn)
@@ -93,6 +143,10 @@ func (c *Client) handleReceivedUDPPackets(conn net.Conn) error {
 		var data = make([]byte, c.udpPayload)
 		n, _, err := udpConn.ReadFromUDP(data)
 		if err != nil {
+			if c.isClosed() {
+				return err
+			}
+
 			continue
 		}
 
@@ -114,6 +168,10 @@ func (c *Client) handleReceivedUDPPackets(conn net.Conn) error {
 		c.response.Header = receive.Header
 		c.response.Body = receive.Body
 
+		if c.handlePing(receive) {
+			continue
+		}
+
 		operator := int64(convert.BytesToUint32(bType)) + sequence
 		if handler, ok := c.handlerContainer.Load(operator); ok {
 			if v, ok := handler.(Handler); ok {
@@ -182,6 +240,10 @@ func (c *Client) handleReceivedTCPPackets(conn net.Conn) error {
 		c.response.Header = receive.Header
 		c.response.Body = receive.Body
 
+		if c.handlePing(receive) {
+			continue
+		}
+
 		operator := int64(nType) + sequence
 		if handler, ok := c.handlerContainer.Load(operator); ok {
 			if v, ok := handler.(Handler); ok {
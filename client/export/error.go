@@ -0,0 +1,11 @@
+package export
+
+import "errors"
+
+// error
+var (
+	ErrorConnectionLost  = errors.New("linker: connection lost")
+	ErrorClientClosed    = errors.New("linker: client is closed")
+	ErrorReconnectFailed = errors.New("linker: reconnect failed")
+	ErrorRequestCanceled = errors.New("linker: request canceled")
+)
@@ -5,9 +5,11 @@ import (
 	"errors"
 	"hash/crc32"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/wpajqz/linker"
@@ -43,17 +45,31 @@ type ReadyStateCallback interface {
 	OnError(err error)
 }
 
+// ReconnectCallback 重连状态回调，ReadyStateCallback同时实现该接口时，重连过程中会被调用
+type ReconnectCallback interface {
+	OnReconnecting(attempt int)
+	OnReconnected()
+}
+
 // Client 客户端结构体
 type Client struct {
 	conn                    net.Conn
+	network, address        string
 	closed                  bool
+	done                    chan struct{}
+	reconnect               *ReconnectPolicy
+	reconnected             chan struct{}
 	udpPayload              int
 	readyStateCallback      ReadyStateCallback
 	readyState              int
-	mutex                   *sync.Mutex
 	rwMutex                 *sync.RWMutex
 	timeout                 time.Duration
+	heartbeatInterval       int64
+	sequence                int64
+	inflight                int64
 	handlerContainer        sync.Map
+	pending                 sync.Map
+	listeners               sync.Map
 	packet                  chan linker.Packet
 	pluginForPacketSender   []plugin.PacketPlugin
 	pluginForPacketReceiver []plugin.PacketPlugin
@@ -73,10 +89,11 @@ func (f HandlerFunc) Handle(header, body []byte) {
 func NewClient(address string, readyStateCallback ReadyStateCallback) (*Client, error) {
 	c := &Client{
 		readyState:       CONNECTING,
-		mutex:            new(sync.Mutex),
+		sequence:         time.Now().UnixNano(),
 		rwMutex:          new(sync.RWMutex),
 		packet:           make(chan linker.Packet, 1024),
 		handlerContainer: sync.Map{},
+		done:             make(chan struct{}),
 	}
 
 	if readyStateCallback != nil {
@@ -95,10 +112,11 @@ func NewClient(address string, readyStateCallback ReadyStateCallback) (*Client,
 func NewUDPClient(address string, readyStateCallback ReadyStateCallback) (*Client, error) {
 	c := &Client{
 		readyState:       CONNECTING,
-		mutex:            new(sync.Mutex),
+		sequence:         time.Now().UnixNano(),
 		rwMutex:          new(sync.RWMutex),
 		packet:           make(chan linker.Packet, 1024),
 		handlerContainer: sync.Map{},
+		done:             make(chan struct{}),
 	}
 
 	if readyStateCallback != nil {
@@ -115,6 +133,9 @@ func NewUDPClient(address string, readyStateCallback ReadyStateCallback) (*Clien
 
 // GetReadyState 获取链接运行状态
 func (c *Client) GetReadyState() int {
+	c.rwMutex.RLock()
+	defer c.rwMutex.RUnlock()
+
 	return c.readyState
 }
 
@@ -128,26 +149,10 @@ func (c *Client) Ping(param interface{}, callback RequestStatusCallback) error {
 		return errors.New("callback can't be nil")
 	}
 
-	if c.readyState != OPEN {
-		return errors.New("ping getsockopt: connection refuse")
+	if err := c.ready(errors.New("ping getsockopt: connection refuse")); err != nil {
+		return err
 	}
 
-	sequence := time.Now().UnixNano()
-	listener := int64(linker.OperatorHeartbeat) + sequence
-
-	c.handlerContainer.Store(listener, HandlerFunc(func(header, body []byte) {
-		code := c.GetResponseProperty("code")
-		if code != "" {
-			message := c.GetResponseProperty("message")
-			v, _ := strconv.Atoi(code)
-			callback.OnError(v, message)
-		} else {
-			callback.OnSuccess(header, body)
-		}
-
-		c.handlerContainer.Delete(listener)
-	}))
-
 	coder, err := codec.NewCoder(c.contentType)
 	if err != nil {
 		return err
@@ -158,22 +163,36 @@ func (c *Client) Ping(param interface{}, callback RequestStatusCallback) error {
 		return err
 	}
 
-	p, err := linker.NewPacket(linker.OperatorHeartbeat, sequence, c.request.Header, body, c.pluginForPacketSender)
-	if err != nil {
-		return err
+	header := c.request.Header
+	if interval := c.HeartbeatInterval(); interval > 0 {
+		header = append(append([]byte(nil), header...), []byte(linker.HeartbeatIntervalProperty+"="+strconv.FormatInt(int64(interval/time.Millisecond), 10)+";")...)
 	}
 
-	_, err = c.conn.Write(p.Bytes())
+	sequence := c.nextSequence()
+	p, err := linker.NewPacket(linker.OperatorHeartbeat, sequence, header, body, c.pluginForPacketSender)
 	if err != nil {
 		return err
 	}
 
-	if c.timeout != 0 {
-		err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
-		if err != nil {
-			return err
+	c.track(int64(linker.OperatorHeartbeat)+sequence, func(header, body []byte) {
+		code := c.GetResponseProperty("code")
+		if code != "" {
+			message := c.GetResponseProperty("message")
+			v, _ := strconv.Atoi(code)
+			callback.OnError(v, message)
+		} else {
+			// 使用服务端协商后的心跳间隔
+			if v, err := strconv.ParseInt(c.GetResponseProperty(linker.HeartbeatIntervalProperty), 10, 64); err == nil && v > 0 {
+				atomic.StoreInt64(&c.heartbeatInterval, int64(time.Duration(v)*time.Millisecond))
+			}
+
+			callback.OnSuccess(header, body)
 		}
-	}
+	}, func(err error) {
+		callback.OnError(linker.StatusServiceUnavailable, err.Error())
+	})
+
+	c.packet <- p
 
 	return nil
 }
@@ -184,21 +203,34 @@ func (c *Client) SyncSend(operator string, param interface{}, callback RequestSt
 		return errors.New("callback can't be nil")
 	}
 
-	if c.readyState != OPEN {
-		return errors.New("SyncSend getsockopt: connection refuse")
+	if err := c.ready(errors.New("SyncSend getsockopt: connection refuse")); err != nil {
+		return err
+	}
+
+	coder, err := codec.NewCoder(c.contentType)
+	if err != nil {
+		return err
+	}
+
+	body, err := coder.Encoder(param)
+	if err != nil {
+		return err
 	}
 
 	nType := crc32.ChecksumIEEE([]byte(operator))
-	sequence := time.Now().UnixNano()
-	listener := int64(nType) + sequence
+	sequence := c.nextSequence()
+
+	p, err := linker.NewPacket(nType, sequence, c.request.Header, body, c.pluginForPacketSender)
+	if err != nil {
+		return err
+	}
 
-	// 对数据请求的返回状态进行处理,同步阻塞处理机制
-	c.mutex.Lock()
-	quit := make(chan bool)
+	// 对数据请求的返回状态进行处理,同步阻塞处理机制,同一个连接上可以同时有多个请求
+	quit := make(chan bool, 1)
 
 	callback.OnStart()
 
-	c.handlerContainer.Store(listener, HandlerFunc(func(header, body []byte) {
+	c.track(int64(nType)+sequence, func(header, body []byte) {
 		code := c.GetResponseProperty("code")
 		if code != "" {
 			message := c.GetResponseProperty("message")
@@ -209,50 +241,87 @@ func (c *Client) SyncSend(operator string, param interface{}, callback RequestSt
 		}
 
 		callback.OnEnd()
-
-		c.handlerContainer.Delete(listener)
 		quit <- true
-	}))
-
-	coder, err := codec.NewCoder(c.contentType)
-	if err != nil {
-		return err
-	}
-
-	body, err := coder.Encoder(param)
-	if err != nil {
-		return err
-	}
-
-	p, err := linker.NewPacket(nType, sequence, c.request.Header, body, c.pluginForPacketSender)
-	if err != nil {
-		return err
-	}
+	}, func(err error) {
+		callback.OnError(linker.StatusServiceUnavailable, err.Error())
+		callback.OnEnd()
+		quit <- true
+	})
 
 	c.packet <- p
 	<-quit
-	c.mutex.Unlock()
 
 	return nil
 }
 
 // AsyncSend 向服务端发送请求，异步处理服务端返回结果
 func (c *Client) AsyncSend(operator string, param interface{}, callback RequestStatusCallback) error {
+	_, err := c.asyncSend(crc32.ChecksumIEEE([]byte(operator)), c.request.Header, param, callback)
+	return err
+}
+
+// AsyncSendWithProperties 向服务端发送请求，properties只对本次请求生效，异步处理服务端返回结果，
+// 返回的cancel用于取消还没有收到响应的请求，取消后不再回调callback
+func (c *Client) AsyncSendWithProperties(operator string, properties map[string]string, param interface{}, callback RequestStatusCallback) (cancel func(), err error) {
+	return c.asyncSend(crc32.ChecksumIEEE([]byte(operator)), c.withProperties(properties), param, callback)
+}
+
+// AsyncSendBatch 在一个数据帧中发送多个子请求，响应内容为按照顺序排列的子请求结果
+func (c *Client) AsyncSendBatch(properties map[string]string, requests []linker.BatchRequest, callback RequestStatusCallback) (cancel func(), err error) {
+	return c.asyncSend(linker.OperatorBatch, c.withProperties(properties), requests, callback)
+}
+
+// withProperties 在公共请求属性的基础上添加只对本次请求生效的属性
+func (c *Client) withProperties(properties map[string]string) []byte {
+	header := c.request.Header
+	if len(properties) == 0 {
+		return header
+	}
+
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	header = append([]byte(nil), header...)
+	for _, k := range keys {
+		header = setProperty(header, k, properties[k])
+	}
+
+	return header
+}
+
+func (c *Client) asyncSend(nType uint32, header []byte, param interface{}, callback RequestStatusCallback) (func(), error) {
 	if callback == nil {
-		return errors.New("callback can't be nil")
+		return nil, errors.New("callback can't be nil")
 	}
 
-	if c.readyState != OPEN {
-		return errors.New("AsyncSend getsockopt: connection refuse")
+	if err := c.ready(errors.New("AsyncSend getsockopt: connection refuse")); err != nil {
+		return nil, err
 	}
 
-	nType := crc32.ChecksumIEEE([]byte(operator))
-	sequence := time.Now().UnixNano()
+	coder, err := codec.NewCoder(c.contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := coder.Encoder(param)
+	if err != nil {
+		return nil, err
+	}
+
+	sequence := c.nextSequence()
+
+	p, err := linker.NewPacket(nType, sequence, header, body, c.pluginForPacketSender)
+	if err != nil {
+		return nil, err
+	}
 
-	listener := int64(nType) + sequence
 	callback.OnStart()
 
-	c.handlerContainer.Store(listener, HandlerFunc(func(header, body []byte) {
+	c.track(int64(nType)+sequence, func(header, body []byte) {
 		code := c.GetResponseProperty("code")
 		if code != "" {
 			message := c.GetResponseProperty("message")
@@ -263,74 +332,59 @@ func (c *Client) AsyncSend(operator string, param interface{}, callback RequestS
 		}
 
 		callback.OnEnd()
+	}, func(err error) {
+		if err == ErrorRequestCanceled {
+			return
+		}
 
-		c.handlerContainer.Delete(listener)
-	}))
+		callback.OnError(linker.StatusServiceUnavailable, err.Error())
+		callback.OnEnd()
+	})
 
-	coder, err := codec.NewCoder(c.contentType)
-	if err != nil {
-		return err
-	}
+	c.packet <- p
 
-	body, err := coder.Encoder(param)
-	if err != nil {
-		return err
+	return func() {
+		c.cancel(nType, sequence)
+	}, nil
+}
+
+// cancel 取消还没有收到响应的请求，并发送取消帧通知服务端停止处理
+func (c *Client) cancel(operator uint32, sequence int64) {
+	v, ok := c.pending.Load(int64(operator) + sequence)
+	if !ok {
+		return
 	}
 
-	p, err := linker.NewPacket(nType, sequence, c.request.Header, body, c.pluginForPacketSender)
+	v.(*pendingCall).fail(ErrorRequestCanceled)
+
+	p, err := linker.NewPacket(linker.OperatorCancel, sequence, nil, nil, c.pluginForPacketSender)
 	if err != nil {
-		return err
+		return
 	}
 
-	c.packet <- p
-
-	return nil
+	select {
+	case c.packet <- p:
+	default:
+	}
 }
 
-// AddMessageListener 添加事件监听器
+// AddMessageListener 添加事件监听器，自动重连后会重新注册
 func (c *Client) AddMessageListener(topic string, callback Handler) error {
 	if callback == nil {
 		return errors.New("callback can't be nil")
 	}
 
-	if c.readyState != OPEN {
-		return errors.New("ping getsockopt: connection refuse")
-	}
-
-	sequence := time.Now().UnixNano()
-	listener := int64(linker.OperatorRegisterListener) + sequence
-
-	// 对数据请求的返回状态进行处理,同步阻塞处理机制
-	c.mutex.Lock()
-	quit := make(chan bool)
-
-	var errRequest error
-	c.handlerContainer.Store(listener, HandlerFunc(func(header, body []byte) {
-		code := c.GetResponseProperty("code")
-		if code != "" {
-			message := c.GetResponseProperty("message")
-			errRequest = errors.New(message)
-		} else {
-			c.handlerContainer.Store(int64(crc32.ChecksumIEEE([]byte(topic))), callback)
-		}
-
-		c.handlerContainer.Delete(listener)
-		quit <- true
-	}))
-
-	p, err := linker.NewPacket(linker.OperatorRegisterListener, sequence, c.request.Header, []byte(topic), c.pluginForPacketSender)
-	if err != nil {
+	if err := c.ready(errors.New("ping getsockopt: connection refuse")); err != nil {
 		return err
 	}
 
-	c.packet <- p
-	<-quit
-
-	if errRequest != nil {
-		return errRequest
+	c.handlerContainer.Store(int64(crc32.ChecksumIEEE([]byte(topic))), callback)
+	if err := c.listen(linker.OperatorRegisterListener, topic); err != nil {
+		c.handlerContainer.Delete(int64(crc32.ChecksumIEEE([]byte(topic))))
+		return err
 	}
 
-	c.mutex.Unlock()
+	c.listeners.Store(topic, callback)
 
 	return nil
 }
@@ -355,56 +409,59 @@ func (c *Client) SetPluginForPacketReceiver(plugins ...plugin.PacketPlugin) {
 
 // RemoveMessageListener 移除事件监听器
 func (c *Client) RemoveMessageListener(topic string) error {
-	if c.readyState != OPEN {
-		return errors.New("ping getsockopt: connection refuse")
+	if err := c.ready(errors.New("ping getsockopt: connection refuse")); err != nil {
+		return err
 	}
 
-	sequence := time.Now().UnixNano()
-	listener := int64(linker.OperatorRemoveListener) + sequence
+	if err := c.listen(linker.OperatorRemoveListener, topic); err != nil {
+		return err
+	}
 
-	// 对数据请求的返回状态进行处理,同步阻塞处理机制
-	c.mutex.Lock()
-	quit := make(chan bool)
+	c.listeners.Delete(topic)
+	c.handlerContainer.Delete(int64(crc32.ChecksumIEEE([]byte(topic))))
 
-	var errRequest error
-	c.handlerContainer.Store(listener, HandlerFunc(func(header, body []byte) {
-		code := c.GetResponseProperty("code")
-		if code != "" {
-			message := c.GetResponseProperty("message")
-			errRequest = errors.New(message)
-		} else {
-			c.handlerContainer.Delete(int64(crc32.ChecksumIEEE([]byte(topic))))
-		}
-
-		c.handlerContainer.Delete(listener)
-		quit <- true
-	}))
+	return nil
+}
 
-	p, err := linker.NewPacket(linker.OperatorRemoveListener, sequence, c.request.Header, []byte(topic), c.pluginForPacketSender)
+// listen 向服务端注册或者移除事件监听，同步等待服务端返回结果
+func (c *Client) listen(operator uint32, topic string) error {
+	sequence := c.nextSequence()
+	p, err := linker.NewPacket(operator, sequence, c.request.Header, []byte(topic), c.pluginForPacketSender)
 	if err != nil {
 		return err
 	}
 
-	c.packet <- p
-	<-quit
-
-	if errRequest != nil {
-		return errRequest
-	}
+	quit := make(chan error, 1)
+	c.track(int64(operator)+sequence, func(header, body []byte) {
+		if code := c.GetResponseProperty("code"); code != "" {
+			quit <- errors.New(c.GetResponseProperty("message"))
+		} else {
+			quit <- nil
+		}
+	}, func(err error) {
+		quit <- err
+	})
 
-	c.mutex.Unlock()
+	c.packet <- p
 
-	return nil
+	return <-quit
 }
 
-// SetRequestProperty 设置请求属性
+// SetRequestProperty 设置连接上所有请求共用的请求属性，连接池中的连接由所有调用方共享，
+// 只对单个请求生效的属性使用AsyncSendWithProperties设置
 func (c *Client) SetRequestProperty(key, value string) {
-	v := c.GetRequestProperty(key)
-	old := []byte(key + "=" + v + ";")
-	new := []byte("")
+	c.request.Header = setProperty(c.request.Header, key, value)
+}
+
+// setProperty 替换header中已经存在的属性
+func setProperty(header []byte, key, value string) []byte {
+	for _, v := range strings.Split(string(header), ";") {
+		if kv := strings.Split(v, "="); kv[0] == key && len(kv) > 1 {
+			header = bytes.ReplaceAll(header, []byte(key+"="+kv[1]+";"), nil)
+		}
+	}
 
-	c.request.Header = bytes.ReplaceAll(c.request.Header, old, new)
-	c.request.Header = append(c.request.Header, []byte(key+"="+value+";")...)
+	return append(header, []byte(key+"="+value+";")...)
 }
 
 // GetRequestProperty 获取请求属性
@@ -449,6 +506,26 @@ func (c *Client) Reset() {
 	c.response.Header = nil
 }
 
+// Outstanding 当前连接上等待响应的请求数
+func (c *Client) Outstanding() int {
+	return int(atomic.LoadInt64(&c.inflight))
+}
+
+// nextSequence 生成请求序号，同一个连接上的并发请求通过序号区分
+func (c *Client) nextSequence() int64 {
+	return atomic.AddInt64(&c.sequence, 1)
+}
+
+// SetHeartbeatInterval 设置希望使用的心跳间隔，服务端会在心跳响应中返回协商后的结果
+func (c *Client) SetHeartbeatInterval(d time.Duration) {
+	atomic.StoreInt64(&c.heartbeatInterval, int64(d))
+}
+
+// HeartbeatInterval 获取当前的心跳间隔
+func (c *Client) HeartbeatInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.heartbeatInterval))
+}
+
 // SetTimeout 设置服务端默认超时时间, 单位s
 func (c *Client) SetTimeout(timeout int) {
 	c.timeout = time.Duration(timeout) * time.Second
@@ -456,21 +533,40 @@ func (c *Client) SetTimeout(timeout int) {
 
 // Close 关闭链接
 func (c *Client) Close() error {
+	c.rwMutex.Lock()
+	if c.closed {
+		c.rwMutex.Unlock()
+		return nil
+	}
+
 	c.closed = true
-	return c.conn.Close()
+	close(c.done)
+	conn := c.conn
+	c.rwMutex.Unlock()
+
+	return conn.Close()
 }
 
-func (c *Client) connect(network, address string) error {
-	var err error
+func (c *Client) isClosed() bool {
+	c.rwMutex.RLock()
+	defer c.rwMutex.RUnlock()
 
-	c.conn, err = net.Dial(network, address)
+	return c.closed
+}
+
+func (c *Client) connect(network, address string) error {
+	conn, err := net.Dial(network, address)
 	if err != nil {
 		return err
 	}
 
+	c.rwMutex.Lock()
+	c.network, c.address = network, address
+	c.conn = conn
 	c.readyState = OPEN
+	c.rwMutex.Unlock()
 
-	go c.handleConnection(network, c.conn)
+	go c.handleConnection(network, conn, false)
 
 	return nil
 }
@@ -0,0 +1,260 @@
+package export
+
+import (
+	"math"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wpajqz/linker"
+)
+
+// PendingPolicy 重连期间请求的处理策略
+type PendingPolicy int
+
+const (
+	// PendingFail 重连期间发起的请求以及还未发送的请求直接返回失败
+	PendingFail PendingPolicy = iota
+	// PendingQueue 重连期间发起的请求排队等待，重连成功后继续发送
+	PendingQueue
+)
+
+// ReconnectPolicy 自动重连策略，重连间隔按指数退避增长，并加入随机抖动避免大量客户端同时重连
+type ReconnectPolicy struct {
+	MaxAttempts  int           // 最大重连次数，0表示不限制
+	InitialDelay time.Duration // 第一次重连前的等待时间
+	MaxDelay     time.Duration // 重连间隔上限
+	Multiplier   float64       // 每次重连后间隔的增长倍数
+	Jitter       float64       // 随机抖动比例，取值0~1
+	Pending      PendingPolicy
+}
+
+// DefaultReconnectPolicy 默认重连策略
+var DefaultReconnectPolicy = ReconnectPolicy{
+	InitialDelay: 500 * time.Millisecond,
+	MaxDelay:     30 * time.Second,
+	Multiplier:   2,
+	Jitter:       0.2,
+	Pending:      PendingFail,
+}
+
+// backoff 计算第attempt次重连前需要等待的时间
+func (p ReconnectPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	if d < 0 {
+		d = 0
+	}
+
+	return time.Duration(d)
+}
+
+// pendingCall 等待服务端响应的请求，连接断开时需要通知调用方
+type pendingCall struct {
+	once sync.Once
+	sent int32
+	fail func(err error)
+}
+
+// SetReconnectPolicy 开启自动重连，连接断开后按照策略重新建立连接并恢复事件监听
+func (c *Client) SetReconnectPolicy(policy ReconnectPolicy) {
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = DefaultReconnectPolicy.InitialDelay
+	}
+
+	if policy.Multiplier < 1 {
+		policy.Multiplier = DefaultReconnectPolicy.Multiplier
+	}
+
+	if policy.Jitter > 1 {
+		policy.Jitter = 1
+	}
+
+	c.rwMutex.Lock()
+	c.reconnect = &policy
+	c.rwMutex.Unlock()
+}
+
+// track 记录等待响应的请求，响应和连接断开只会有一个生效
+func (c *Client) track(listener int64, handle func(header, body []byte), fail func(err error)) {
+	call := &pendingCall{}
+	call.fail = func(err error) {
+		call.once.Do(func() {
+			c.handlerContainer.Delete(listener)
+			c.pending.Delete(listener)
+			atomic.AddInt64(&c.inflight, -1)
+			fail(err)
+		})
+	}
+
+	atomic.AddInt64(&c.inflight, 1)
+	c.pending.Store(listener, call)
+	c.handlerContainer.Store(listener, HandlerFunc(func(header, body []byte) {
+		call.once.Do(func() {
+			c.handlerContainer.Delete(listener)
+			c.pending.Delete(listener)
+			atomic.AddInt64(&c.inflight, -1)
+			handle(header, body)
+		})
+	}))
+}
+
+// markSent 标记请求已经写入连接，连接断开时无法确认服务端是否已经处理
+func (c *Client) markSent(p linker.Packet) {
+	if v, ok := c.pending.Load(int64(p.Operator) + p.Sequence); ok {
+		atomic.StoreInt32(&v.(*pendingCall).sent, 1)
+	}
+}
+
+// failPending 通知等待响应的请求失败，all为false时只处理已经发出的请求，还未发送的请求留在队列中等待重连
+func (c *Client) failPending(err error, all bool) {
+	if all {
+	drain:
+		for {
+			select {
+			case <-c.packet:
+			default:
+				break drain
+			}
+		}
+	}
+
+	c.pending.Range(func(key, value interface{}) bool {
+		call := value.(*pendingCall)
+		if all || atomic.LoadInt32(&call.sent) == 1 {
+			call.fail(err)
+		}
+
+		return true
+	})
+}
+
+// ready 检查连接状态，重连过程中根据策略等待重连结果或者直接返回错误
+func (c *Client) ready(err error) error {
+	for {
+		c.rwMutex.RLock()
+		state, reconnected, policy := c.readyState, c.reconnected, c.reconnect
+		c.rwMutex.RUnlock()
+
+		if state == OPEN {
+			return nil
+		}
+
+		if state != CONNECTING || reconnected == nil || policy == nil || policy.Pending != PendingQueue {
+			return err
+		}
+
+		<-reconnected
+	}
+}
+
+// startReconnect 连接断开后进入重连状态
+func (c *Client) startReconnect() {
+	c.rwMutex.Lock()
+	c.readyState = CONNECTING
+	c.reconnected = make(chan struct{})
+	policy := *c.reconnect
+	c.rwMutex.Unlock()
+
+	c.failPending(ErrorConnectionLost, policy.Pending != PendingQueue)
+
+	go c.reconnectLoop(policy)
+}
+
+// reconnectLoop 按照退避策略不断尝试重新建立连接
+func (c *Client) reconnectLoop(policy ReconnectPolicy) {
+	for attempt := 1; policy.MaxAttempts == 0 || attempt <= policy.MaxAttempts; attempt++ {
+		if rc, ok := c.readyStateCallback.(ReconnectCallback); ok {
+			rc.OnReconnecting(attempt)
+		}
+
+		select {
+		case <-time.After(policy.backoff(attempt)):
+		case <-c.done:
+			c.finishReconnect(false)
+			if c.readyStateCallback != nil {
+				c.readyStateCallback.OnClose()
+			}
+
+			return
+		}
+
+		conn, err := net.Dial(c.network, c.address)
+		if err != nil {
+			continue
+		}
+
+		c.rwMutex.Lock()
+		if c.closed {
+			c.rwMutex.Unlock()
+			_ = conn.Close()
+			c.finishReconnect(false)
+			if c.readyStateCallback != nil {
+				c.readyStateCallback.OnClose()
+			}
+
+			return
+		}
+
+		c.conn = conn
+		c.rwMutex.Unlock()
+
+		go c.handleConnection(c.network, conn, true)
+
+		c.finishReconnect(true)
+		c.restoreListeners()
+
+		if rc, ok := c.readyStateCallback.(ReconnectCallback); ok {
+			rc.OnReconnected()
+		}
+
+		return
+	}
+
+	c.finishReconnect(false)
+
+	if c.readyStateCallback != nil {
+		c.readyStateCallback.OnError(ErrorReconnectFailed)
+	}
+}
+
+// finishReconnect 结束本轮重连，唤醒排队等待的请求
+func (c *Client) finishReconnect(ok bool) {
+	c.rwMutex.Lock()
+	if ok {
+		c.readyState = OPEN
+	} else {
+		c.readyState = CLOSED
+	}
+
+	if c.reconnected != nil {
+		close(c.reconnected)
+		c.reconnected = nil
+	}
+	c.rwMutex.Unlock()
+
+	if !ok {
+		c.failPending(ErrorReconnectFailed, true)
+	}
+}
+
+// restoreListeners 重连成功后重新注册之前添加的事件监听器
+func (c *Client) restoreListeners() {
+	c.listeners.Range(func(key, value interface{}) bool {
+		topic := key.(string)
+		if err := c.listen(linker.OperatorRegisterListener, topic); err != nil && c.readyStateCallback != nil {
+			c.readyStateCallback.OnError(err)
+		}
+
+		return true
+	})
+}
@@ -0,0 +1,107 @@
+package export_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/export"
+	"github.com/wpajqz/linker/codec"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+var _ export.ReconnectCallback = new(readyStateCallback)
+
+type readyStateCallback struct {
+	reconnecting chan int
+	reconnected  chan struct{}
+}
+
+func (rc *readyStateCallback) OnOpen() {}
+
+func (rc *readyStateCallback) OnClose() {}
+
+func (rc *readyStateCallback) OnError(err error) {}
+
+func (rc *readyStateCallback) OnReconnecting(attempt int) {
+	rc.reconnecting <- attempt
+}
+
+func (rc *readyStateCallback) OnReconnected() {
+	rc.reconnected <- struct{}{}
+}
+
+func TestReconnect(t *testing.T) {
+	router := linker.NewRouter()
+	router.Route("/publish", linker.HandlerFunc(func(ctx linker.Context) {
+		if err := ctx.Publish("/notify", "hello"); err != nil {
+			ctx.Error(linker.StatusInternalServerError, err.Error())
+		}
+
+		ctx.Success(nil)
+	}))
+	s, address := servertest.Start(t, router)
+
+	rc := &readyStateCallback{reconnecting: make(chan int, 10), reconnected: make(chan struct{}, 1)}
+	c, err := export.NewClient(address, rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.SetContentType(codec.JSON)
+	c.SetReconnectPolicy(export.ReconnectPolicy{InitialDelay: 50 * time.Millisecond, MaxAttempts: 5, Pending: export.PendingQueue})
+
+	received := make(chan struct{}, 1)
+	err = c.AddMessageListener("/notify", export.HandlerFunc(func(header, body []byte) {
+		received <- struct{}{}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务端主动断开连接
+	s.Connections().Range(func(conn *linker.Connection) bool {
+		_ = conn.Close()
+		return true
+	})
+
+	select {
+	case <-rc.reconnected:
+	case <-time.After(3 * time.Second):
+		t.Fatal("reconnect timeout")
+	}
+
+	if len(rc.reconnecting) == 0 {
+		t.Fatal("OnReconnecting not called")
+	}
+
+	if c.GetReadyState() != export.OPEN {
+		t.Fatalf("ready state: %d", c.GetReadyState())
+	}
+
+	err = c.SyncSend("/publish", nil, &statusCallback{t: t})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-received:
+	case <-time.After(3 * time.Second):
+		t.Fatal("listener not restored after reconnect")
+	}
+}
+
+type statusCallback struct {
+	t *testing.T
+}
+
+func (sc *statusCallback) OnSuccess(header, body []byte) {}
+
+func (sc *statusCallback) OnError(status int, message string) {
+	sc.t.Errorf("request error: %d %s", status, message)
+}
+
+func (sc *statusCallback) OnStart() {}
+
+func (sc *statusCallback) OnEnd() {}
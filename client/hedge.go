@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// HedgePolicy 对冲请求策略，请求超过Delay没有响应时向另一个地址发送相同的请求，
+// 使用最先返回的成功结果并取消其余请求，只应该用于幂等的读请求
+type HedgePolicy struct {
+	Delay     time.Duration // 发送对冲请求前等待的时间，一般设置为请求耗时的p95
+	MaxHedges int           // 最多额外发送的请求数，默认1
+	Budget    float64       // 对冲请求占全部请求的最大比例，默认0.1
+}
+
+// maxHedgeTokens 对冲请求的令牌上限，限制流量突增时的对冲请求数
+const maxHedgeTokens = 10
+
+// hedgeBudget 对冲请求的令牌桶，每个请求增加Budget个令牌，每个对冲请求消耗一个令牌
+type hedgeBudget struct {
+	mu     sync.Mutex
+	tokens float64
+}
+
+func (b *hedgeBudget) deposit(ratio float64) {
+	b.mu.Lock()
+	if b.tokens += ratio; b.tokens > maxHedgeTokens {
+		b.tokens = maxHedgeTokens
+	}
+	b.mu.Unlock()
+}
+
+func (b *hedgeBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+type attemptsKey struct{}
+
+// attempts 同一个请求已经使用过的地址，对冲请求优先选择其他地址
+type attempts struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func attempted(ctx context.Context) *attempts {
+	a, _ := ctx.Value(attemptsKey{}).(*attempts)
+	return a
+}
+
+func (a *attempts) add(address string) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	a.list = append(a.list, address)
+	a.mu.Unlock()
+}
+
+func (a *attempts) addresses() []string {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]string(nil), a.list...)
+}
+
+// hedge 按照请求类型对应的对冲策略发送请求，返回时取消所有还没有完成的请求
+func (c *Client) hedge(ctx context.Context, operator string, req, resp interface{}, invoker Invoker) error {
+	policy, ok := c.options.hedges[operator]
+	if !ok || policy.Delay <= 0 {
+		return invoker(ctx, operator, req, resp)
+	}
+
+	if policy.MaxHedges <= 0 {
+		policy.MaxHedges = 1
+	}
+
+	if policy.Budget <= 0 {
+		policy.Budget = 0.1
+	}
+
+	c.budget.deposit(policy.Budget)
+
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, attemptsKey{}, &attempts{}))
+	defer cancel()
+
+	type result struct {
+		resp interface{}
+		err  error
+	}
+
+	results := make(chan result, policy.MaxHedges+1)
+	launch := func() {
+		r := newResponse(resp)
+		go func() {
+			results <- result{resp: r, err: invoker(ctx, operator, req, r)}
+		}()
+	}
+
+	launch()
+	pending, hedges := 1, 0
+
+	timer := time.NewTimer(policy.Delay)
+	defer timer.Stop()
+
+	var err error
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				copyResponse(resp, r.resp)
+				return nil
+			}
+
+			if err = r.err; pending == 0 {
+				return err
+			}
+		case <-timer.C:
+			if hedges < policy.MaxHedges && c.budget.withdraw() {
+				launch()
+				pending++
+				hedges++
+				timer.Reset(policy.Delay)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// newResponse 每个请求解码到独立的对象中，避免同时写入resp
+func newResponse(resp interface{}) interface{} {
+	if resp == nil {
+		return nil
+	}
+
+	return reflect.New(reflect.TypeOf(resp).Elem()).Interface()
+}
+
+func copyResponse(dst, src interface{}) {
+	if dst != nil {
+		reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+	}
+}
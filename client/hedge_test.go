@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/balancer"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+// firstBalancer 总是选择第一个候选地址
+type firstBalancer struct{}
+
+func (firstBalancer) Pick(endpoints []balancer.Endpoint, properties map[string]string) (balancer.Endpoint, error) {
+	return endpoints[0], nil
+}
+
+func TestHedge(t *testing.T) {
+	slow, fast := servertest.Address(t), servertest.Address(t)
+
+	var canceled int64
+	for _, address := range []string{slow, fast} {
+		s := linker.NewServer(linker.WithTCPEndpoint(linker.Endpoint{Address: address}))
+
+		router := linker.NewRouter()
+		router.Route("/read", func(address string) linker.HandlerFunc {
+			return func(ctx linker.Context) {
+				if address == slow {
+					select {
+					case <-ctx.Ctx().Done():
+						if ctx.Ctx().Err() == context.Canceled {
+							atomic.AddInt64(&canceled, 1)
+						}
+					case <-time.After(300 * time.Millisecond):
+					}
+				}
+
+				ctx.Success(address)
+			}
+		}(address))
+		s.BindRouter(router)
+
+		servertest.Run(t, s, address)
+	}
+
+	c, err := NewClient([]string{slow, fast}, Balancer(firstBalancer{}), Hedge(HedgePolicy{Delay: 50 * time.Millisecond, Budget: 1}, "/read"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	start := time.Now()
+
+	var address string
+	if err := c.Invoke(context.Background(), "/read", nil, &address); err != nil {
+		t.Fatal(err)
+	}
+
+	if address != fast || time.Since(start) > 200*time.Millisecond {
+		t.Fatalf("unexpected result: %s, %s", address, time.Since(start))
+	}
+
+	// 较慢的请求收到取消帧
+	time.Sleep(100 * time.Millisecond)
+	if atomic.LoadInt64(&canceled) != 1 {
+		t.Fatalf("slow request not canceled: %d", canceled)
+	}
+
+	// 对冲请求的比例受到限制
+	limited, err := NewClient([]string{slow, fast}, Balancer(firstBalancer{}), Hedge(HedgePolicy{Delay: 50 * time.Millisecond, Budget: 0.5}, "/read"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer limited.Close()
+
+	hedged := 0
+	for i := 0; i < 4; i++ {
+		if err := limited.Invoke(context.Background(), "/read", nil, &address); err != nil {
+			t.Fatal(err)
+		}
+
+		if address == fast {
+			hedged++
+		}
+	}
+
+	if hedged != 2 {
+		t.Fatalf("unexpected hedged requests: %d", hedged)
+	}
+}
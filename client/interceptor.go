@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+
+	"github.com/wpajqz/linker/client/export"
+)
+
+type (
+	// Invoker 发送请求，并把响应解码到resp中，resp为nil时忽略响应内容
+	Invoker func(ctx context.Context, operator string, req, resp interface{}) error
+
+	// UnaryInterceptor 请求拦截器，调用invoker继续处理请求，可以在请求前后添加日志、监控、认证信息等
+	UnaryInterceptor func(ctx context.Context, operator string, req, resp interface{}, invoker Invoker) error
+
+	// Streamer 订阅topic，收到推送的消息时调用handler，ctx结束时取消订阅
+	Streamer func(ctx context.Context, topic string, handler export.Handler) error
+
+	// StreamInterceptor 订阅拦截器，可以包装handler处理每一条推送的消息
+	StreamInterceptor func(ctx context.Context, topic string, handler export.Handler, streamer Streamer) error
+
+	// Guard 按照实际发送请求的地址检查请求，例如熔断器，Available返回false的地址不会被负载均衡选中，
+	// Allow返回错误时不发送请求，允许时返回的done在请求结束时调用，code为响应状态码，0表示请求成功，
+	// 连接不可用时code为StatusServiceUnavailable，请求超时时code为StatusGatewayTimeout，请求被取消时code为NoResult
+	Guard interface {
+		Available(address string) bool
+		Allow(address, operator string) (done func(code int), err error)
+	}
+)
+
+// NoResult 请求被取消或者没有发送出去时传给Guard的状态码，不计入请求结果
+const NoResult = -1
+
+type (
+	propertiesKey struct{}
+	guardKey      struct{}
+)
+
+// WithProperty 设置只对本次请求生效的请求属性，拦截器可以通过该方法添加认证信息等
+func WithProperty(ctx context.Context, key, value string) context.Context {
+	properties := make(map[string]string)
+	for k, v := range Properties(ctx) {
+		properties[k] = v
+	}
+
+	properties[key] = value
+
+	return context.WithValue(ctx, propertiesKey{}, properties)
+}
+
+// Properties 获取ctx中保存的请求属性
+func Properties(ctx context.Context) map[string]string {
+	properties, _ := ctx.Value(propertiesKey{}).(map[string]string)
+	return properties
+}
+
+// WithGuard 设置本次请求使用的Guard，拦截器可以通过该方法检查每一次尝试实际发送的地址
+func WithGuard(ctx context.Context, g Guard) context.Context {
+	return context.WithValue(ctx, guardKey{}, g)
+}
+
+func guard(ctx context.Context) Guard {
+	g, _ := ctx.Value(guardKey{}).(Guard)
+	return g
+}
+
+// chainUnaryInterceptors 按照添加顺序执行拦截器，第一个拦截器最先执行
+func chainUnaryInterceptors(interceptors []UnaryInterceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, operator string, req, resp interface{}) error {
+			return interceptor(ctx, operator, req, resp, next)
+		}
+	}
+
+	return invoker
+}
+
+// chainStreamInterceptors 按照添加顺序执行拦截器，第一个拦截器最先执行
+func chainStreamInterceptors(interceptors []StreamInterceptor, streamer Streamer) Streamer {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], streamer
+		streamer = func(ctx context.Context, topic string, handler export.Handler) error {
+			return interceptor(ctx, topic, handler, next)
+		}
+	}
+
+	return streamer
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/export"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+func TestInterceptor(t *testing.T) {
+	router := linker.NewRouter()
+	router.Route("/token", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Success(ctx.GetRequestProperty("token"))
+	}))
+	router.Route("/fail", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Error(linker.StatusForbidden, "forbidden")
+	}))
+	router.Route("/sleep", linker.HandlerFunc(func(ctx linker.Context) {
+		time.Sleep(200 * time.Millisecond)
+		ctx.Success(nil)
+	}))
+	router.Route("/publish", linker.HandlerFunc(func(ctx linker.Context) {
+		_ = ctx.Publish("/news", "hello")
+		ctx.Success(nil)
+	}))
+	_, address := servertest.Start(t, router)
+
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+
+	logger := func(ctx context.Context, operator string, req, resp interface{}, invoker Invoker) error {
+		mu.Lock()
+		calls = append(calls, "log:"+operator)
+		mu.Unlock()
+
+		return invoker(ctx, operator, req, resp)
+	}
+
+	auth := func(ctx context.Context, operator string, req, resp interface{}, invoker Invoker) error {
+		mu.Lock()
+		calls = append(calls, "auth:"+operator)
+		mu.Unlock()
+
+		return invoker(WithProperty(ctx, "token", "secret"), operator, req, resp)
+	}
+
+	received := make(chan string, 10)
+	counter := func(ctx context.Context, topic string, handler export.Handler, streamer Streamer) error {
+		return streamer(ctx, topic, export.HandlerFunc(func(header, body []byte) {
+			received <- topic
+			handler.Handle(header, body)
+		}))
+	}
+
+	c, err := NewClient([]string{address}, WithUnaryInterceptor(logger, auth), WithStreamInterceptor(counter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var token string
+	if err := c.Invoke(context.Background(), "/token", nil, &token); err != nil {
+		t.Fatal(err)
+	}
+
+	if token != "secret" || len(calls) != 2 || calls[0] != "log:/token" || calls[1] != "auth:/token" {
+		t.Fatalf("unexpected result: %s %v", token, calls)
+	}
+
+	err = c.Invoke(context.Background(), "/fail", nil, nil)
+	if se, ok := err.(*StatusError); !ok || se.Code != linker.StatusForbidden {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := c.Invoke(ctx, "/sleep", nil, nil); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sctx, stop := context.WithCancel(context.Background())
+	handled := make(chan struct{}, 10)
+	err = c.Subscribe(sctx, "/news", export.HandlerFunc(func(header, body []byte) {
+		handled <- struct{}{}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Invoke(context.Background(), "/publish", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case topic := <-received:
+		<-handled
+		if topic != "/news" {
+			t.Fatalf("unexpected topic: %s", topic)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stream interceptor not called")
+	}
+
+	// 取消订阅以后不再收到推送
+	stop()
+	time.Sleep(100 * time.Millisecond)
+
+	if err := c.Invoke(context.Background(), "/publish", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-received:
+		t.Fatal("message received after unsubscribe")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -3,6 +3,8 @@ package client
 import (
 	"time"
 
+	"github.com/wpajqz/linker/client/balancer"
+	"github.com/wpajqz/linker/client/export"
 	"github.com/wpajqz/linker/plugin"
 )
 
@@ -11,12 +13,26 @@ type (
 		network                 string
 		udpPayload              int
 		dialTimeout             time.Duration
-		initialCap              int
-		maxCap                  int
+		minConns                int
+		maxConns                int
+		maxStreams              int
+		maxHeartbeatFailures    int
 		contentType             string
 		idleTimeout             time.Duration
+		heartbeatInterval       time.Duration
 		onOpen, onClose         func()
 		onError                 func(error)
+		onReconnecting          func(int)
+		onReconnected           func()
+		reconnect               *export.ReconnectPolicy
+		balancer                balancer.Balancer
+		unaryInterceptors       []UnaryInterceptor
+		streamInterceptors      []StreamInterceptor
+		retries                 map[string]RetryPolicy
+		hedges                  map[string]HedgePolicy
+		weights                 map[string]int
+		resolver                Resolver
+		drainTimeout            time.Duration
 		ext                     map[string]string
 		pluginForPacketSender   []plugin.PacketPlugin
 		pluginForPacketReceiver []plugin.PacketPlugin
@@ -49,24 +65,57 @@ func ContentType(mime string) Option {
 	}
 }
 
-func InitialCapacity(n int) Option {
+// 每个地址保持的最少连接数
+func MinConns(n int) Option {
 	return Option(func(o *options) {
-		o.initialCap = n
+		o.minConns = n
 	})
 }
 
-func MaxCapacity(n int) Option {
+// 每个地址允许建立的最多连接数
+func MaxConns(n int) Option {
 	return Option(func(o *options) {
-		o.maxCap = n
+		o.maxConns = n
 	})
 }
 
+// 单个连接上同时处理的请求数超过该值时，优先新建连接分担请求
+func MaxStreams(n int) Option {
+	return Option(func(o *options) {
+		o.maxStreams = n
+	})
+}
+
+// 心跳连续失败的次数超过该值时，连接会从连接池中移除
+func MaxHeartbeatFailures(n int) Option {
+	return Option(func(o *options) {
+		o.maxHeartbeatFailures = n
+	})
+}
+
+// Deprecated: 使用MinConns
+func InitialCapacity(n int) Option {
+	return MinConns(n)
+}
+
+// Deprecated: 使用MaxConns
+func MaxCapacity(n int) Option {
+	return MaxConns(n)
+}
+
 func IdleTimeout(timeout time.Duration) Option {
 	return Option(func(o *options) {
 		o.idleTimeout = timeout
 	})
 }
 
+// 心跳间隔，服务端可能会返回协商后的心跳间隔
+func HeartbeatInterval(d time.Duration) Option {
+	return Option(func(o *options) {
+		o.heartbeatInterval = d
+	})
+}
+
 func WithOnOpen(fn func()) Option {
 	return Option(func(o *options) {
 		o.onOpen = fn
@@ -85,6 +134,53 @@ func WithOnError(fn func(err error)) Option {
 	})
 }
 
+// 连接断开后按照策略自动重连，并恢复已经添加的事件监听器
+func Reconnect(policy export.ReconnectPolicy) Option {
+	return Option(func(o *options) {
+		o.reconnect = &policy
+	})
+}
+
+func WithOnReconnecting(fn func(attempt int)) Option {
+	return Option(func(o *options) {
+		o.onReconnecting = fn
+	})
+}
+
+func WithOnReconnected(fn func()) Option {
+	return Option(func(o *options) {
+		o.onReconnected = fn
+	})
+}
+
+// 多个服务端地址之间的负载均衡策略，默认轮询
+func Balancer(b balancer.Balancer) Option {
+	return Option(func(o *options) {
+		o.balancer = b
+	})
+}
+
+// 服务端地址的权重，用于加权负载均衡
+func Weights(weights map[string]int) Option {
+	return Option(func(o *options) {
+		o.weights = weights
+	})
+}
+
+// 通过解析器获取服务端地址，地址变化时连接池自动增加或者移除连接
+func WithResolver(r Resolver) Option {
+	return Option(func(o *options) {
+		o.resolver = r
+	})
+}
+
+// 地址被移除以后等待连接上的请求处理完成的最长时间
+func DrainTimeout(d time.Duration) Option {
+	return Option(func(o *options) {
+		o.drainTimeout = d
+	})
+}
+
 func Ext(ext map[string]string) Option {
 	return Option(func(o *options) {
 		o.ext = ext
@@ -102,3 +198,47 @@ func PluginForPacketReceiver(plugins ...plugin.PacketPlugin) Option {
 		o.pluginForPacketReceiver = append(o.pluginForPacketReceiver, plugins...)
 	}
 }
+
+// WithUnaryInterceptor 添加Invoke使用的请求拦截器，按照添加顺序执行
+func WithUnaryInterceptor(interceptors ...UnaryInterceptor) Option {
+	return func(o *options) {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptor 添加Subscribe使用的订阅拦截器，按照添加顺序执行
+func WithStreamInterceptor(interceptors ...StreamInterceptor) Option {
+	return func(o *options) {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+	}
+}
+
+// Retry 设置请求失败时的重试策略，operators为空时作为所有请求的默认策略
+func Retry(policy RetryPolicy, operators ...string) Option {
+	return func(o *options) {
+		if o.retries == nil {
+			o.retries = make(map[string]RetryPolicy)
+		}
+
+		if len(operators) == 0 {
+			o.retries[""] = policy
+		}
+
+		for _, operator := range operators {
+			o.retries[operator] = policy
+		}
+	}
+}
+
+// Hedge 为幂等的读请求设置对冲策略，请求超过policy.Delay没有响应时向另一个地址发送相同的请求
+func Hedge(policy HedgePolicy, operators ...string) Option {
+	return func(o *options) {
+		if o.hedges == nil {
+			o.hedges = make(map[string]HedgePolicy)
+		}
+
+		for _, operator := range operators {
+			o.hedges[operator] = policy
+		}
+	}
+}
@@ -1,94 +1,620 @@
 package client
 
 import (
-	"fmt"
-	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/silenceper/pool"
 	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/balancer"
 	"github.com/wpajqz/linker/client/export"
 )
 
-var interval int64 = 60
+type (
+	// conn 连接池中的连接，同一个连接上可以同时处理多个请求
+	conn struct {
+		*export.Client
+		address  string
+		failures int32
+		lastUsed int64
+	}
 
-func (c *Client) newExportPool() (pool.Pool, error) {
-	// ping请求的回调，出错的时候调用
-	cb := RequestStatusCallback{
-		Error: func(code int, msg string) {
-			log.Printf("brpc error: %s\n", msg)
-		},
+	// endpoint 同一个服务端地址上的所有连接
+	endpoint struct {
+		address string
+		conns   []*conn
+		dialing int
+		weight  int
 	}
 
-	// factory 创建连接的方法
-	factory := func() (interface{}, error) {
-		var (
-			exportClient *export.Client
-			err          error
-		)
+	// Stats 连接池状态
+	Stats struct {
+		Conns   int // 连接总数
+		InUse   int // 有请求正在处理的连接数
+		Idle    int // 没有请求的连接数
+		Pending int // 等待建立连接的请求数
+	}
 
-		// 出现错误的时候不退出，继续等待服务端恢复以后进行重连
-		address, err := c.getAddress()
-		if err != nil {
-			c.fillAddress(c.address)
-			if c.options.onError != nil {
-				c.options.onError(err)
+	pool struct {
+		options   options
+		mu        sync.Mutex
+		endpoints map[string]*endpoint
+		addresses []string
+		pending   map[string]int
+		done      chan struct{}
+		closeOnce sync.Once
+	}
+)
+
+func newPool(options options) (*pool, error) {
+	p := &pool{
+		options:   options,
+		endpoints: make(map[string]*endpoint),
+		pending:   make(map[string]int),
+		done:      make(chan struct{}),
+	}
+
+	ch, err := options.resolver.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	// 等待第一次解析结果，每个地址预先建立最少数量的连接，所有地址都不可用时返回错误
+	var list []Address
+	select {
+	case list = <-ch:
+	case <-time.After(options.dialTimeout):
+	}
+
+	added := p.update(list)
+	for _, addr := range added {
+		if e := p.fill(addr); e != nil {
+			err = e
+			if p.options.onError != nil {
+				p.options.onError(e)
+			}
+		}
+	}
+
+	if p.stats("").Conns == 0 && err != nil {
+		p.close()
+		return nil, err
+	}
+
+	go p.watch(ch)
+
+	return p, nil
+}
+
+// watch 根据解析器推送的地址列表增加或者移除地址
+func (p *pool) watch(ch <-chan []Address) {
+	for {
+		select {
+		case list, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			for _, addr := range p.update(list) {
+				go func(addr string) {
+					if err := p.fill(addr); err != nil && p.options.onError != nil {
+						p.options.onError(err)
+					}
+				}(addr)
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// update 更新地址列表，返回新增的地址，已经不存在的地址上的连接在请求处理完成以后关闭
+func (p *pool) update(list []Address) []string {
+	p.mu.Lock()
+
+	var (
+		added     []string
+		removed   []*endpoint
+		seen      = make(map[string]struct{}, len(list))
+		addresses = make([]string, 0, len(list))
+	)
+
+	for _, a := range list {
+		if _, ok := seen[a.Addr]; ok || a.Addr == "" {
+			continue
+		}
+
+		seen[a.Addr] = struct{}{}
+		addresses = append(addresses, a.Addr)
+
+		e, ok := p.endpoints[a.Addr]
+		if !ok {
+			e = &endpoint{address: a.Addr}
+			p.endpoints[a.Addr] = e
+			added = append(added, a.Addr)
+		}
+
+		e.weight = a.Weight
+	}
+
+	for addr, e := range p.endpoints {
+		if _, ok := seen[addr]; !ok {
+			delete(p.endpoints, addr)
+			delete(p.pending, addr)
+			removed = append(removed, e)
+		}
+	}
+
+	p.addresses = addresses
+	p.mu.Unlock()
+
+	for _, e := range removed {
+		go p.drain(e)
+	}
+
+	return added
+}
+
+// drain 等待连接上的请求处理完成以后关闭连接，超过drainTimeout时强制关闭
+func (p *pool) drain(e *endpoint) {
+	p.mu.Lock()
+	list := e.conns
+	e.conns = nil
+	p.mu.Unlock()
+
+	deadline := time.Now().Add(p.options.drainTimeout)
+	for _, c := range list {
+		for c.Outstanding() > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		_ = c.Close()
+	}
+}
+
+// get 通过负载均衡策略选择地址，再从地址上选择一个可用的连接，所有连接都比较繁忙并且没有达到上限时新建连接
+func (p *pool) get(properties map[string]string, available func(address string) bool, exclude ...string) (*conn, error) {
+	select {
+	case <-p.done:
+		return nil, ErrorPoolClosed
+	default:
+	}
+
+	candidates := p.candidates()
+	if len(candidates) == 0 {
+		return nil, ErrorNoAvailableAddress
+	}
+
+	candidates = p.available(candidates, available)
+	candidates = p.exclude(candidates, exclude)
+
+	err := ErrorNoAvailableAddress
+	for len(candidates) > 0 {
+		e, perr := p.options.balancer.Pick(candidates, properties)
+		if perr != nil {
+			return nil, perr
+		}
+
+		c, gerr := p.getFrom(e.Address)
+		if gerr == nil {
+			return c, nil
+		}
+
+		// 选中的地址不可用时从候选地址中移除，重新选择
+		err = gerr
+		for i, v := range candidates {
+			if v.Address == e.Address {
+				candidates = append(candidates[:i], candidates[i+1:]...)
+				break
 			}
 		}
+	}
+
+	return nil, err
+}
+
+// candidates 所有地址以及地址上正在处理的请求数
+func (p *pool) candidates() []balancer.Endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	list := make([]balancer.Endpoint, 0, len(p.addresses))
+	for _, address := range p.addresses {
+		e := p.endpoints[address]
+
+		var outstanding int
+		for _, c := range e.conns {
+			outstanding += c.Outstanding()
+		}
+
+		weight := e.weight
+		if weight <= 0 {
+			weight = p.options.weights[address]
+		}
 
-		if c.options.network == linker.NetworkTCP {
-			exportClient, err = export.NewClient(address, &ReadyStateCallback{Open: c.options.onOpen, Close: c.options.onClose, Error: c.options.onError })
+		list = append(list, balancer.Endpoint{Address: address, Weight: weight, Outstanding: outstanding})
+	}
+
+	return list
+}
+
+// exclude 排除已经使用过的地址，全部地址都被排除时保留所有地址
+func (p *pool) exclude(candidates []balancer.Endpoint, addresses []string) []balancer.Endpoint {
+	if len(addresses) == 0 {
+		return candidates
+	}
+
+	list := make([]balancer.Endpoint, 0, len(candidates))
+	for _, e := range candidates {
+		excluded := false
+		for _, address := range addresses {
+			if e.Address == address {
+				excluded = true
+				break
+			}
+		}
+
+		if !excluded {
+			list = append(list, e)
+		}
+	}
+
+	if len(list) == 0 {
+		return candidates
+	}
+
+	return list
+}
+
+// available 排除不可用的地址，例如熔断器已经打开的地址，全部地址都不可用时保留所有地址，由Guard直接返回错误
+func (p *pool) available(candidates []balancer.Endpoint, available func(address string) bool) []balancer.Endpoint {
+	if available == nil {
+		return candidates
+	}
+
+	list := make([]balancer.Endpoint, 0, len(candidates))
+	for _, e := range candidates {
+		if available(e.Address) {
+			list = append(list, e)
+		}
+	}
+
+	if len(list) == 0 {
+		return candidates
+	}
+
+	return list
+}
+
+func (p *pool) getFrom(address string) (*conn, error) {
+	p.mu.Lock()
+
+	e, ok := p.endpoints[address]
+	if !ok {
+		p.mu.Unlock()
+		return nil, ErrorNoAvailableAddress
+	}
+
+	// 已经关闭的连接不会再恢复，立即移除，避免在下一次心跳检查之前一直占用连接数上限，
+	// 正在重连的连接仍然保留，重连成功以后订阅关系会自动恢复
+	var closed []*conn
+	conns := e.conns[:0]
+	for _, c := range e.conns {
+		if c.GetReadyState() == export.CLOSED {
+			closed = append(closed, c)
+			continue
+		}
+
+		conns = append(conns, c)
+	}
+	e.conns = conns
+
+	for _, c := range closed {
+		go c.Close()
+	}
+
+	var best *conn
+	for _, c := range e.conns {
+		if c.GetReadyState() != export.OPEN {
+			continue
+		}
+
+		if best == nil || c.Outstanding() < best.Outstanding() {
+			best = c
+		}
+	}
+
+	total := len(e.conns) + e.dialing
+	if best != nil && (best.Outstanding() < p.options.maxStreams || total >= p.options.maxConns) {
+		p.mu.Unlock()
+		best.touch()
+		return best, nil
+	}
+
+	if total >= p.options.maxConns {
+		p.mu.Unlock()
+		return nil, ErrorNoAvailableConn
+	}
+
+	e.dialing++
+	p.pending[address]++
+	p.mu.Unlock()
+
+	c, err := p.dial(address)
+
+	p.mu.Lock()
+	e.dialing--
+	p.pending[address]--
+	if err == nil {
+		if p.endpoints[address] == e {
+			e.conns = append(e.conns, c)
 		} else {
-			exportClient, err = export.NewUDPClient(address, &ReadyStateCallback{Open: c.options.onOpen, Close: c.options.onClose, Error: c.options.onError})
+			// 建立连接的过程中地址已经被移除
+			p.mu.Unlock()
+			_ = c.Close()
+			return nil, ErrorNoAvailableAddress
+		}
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		if best != nil {
+			best.touch()
+			return best, nil
 		}
 
+		return nil, err
+	}
+
+	c.touch()
+
+	return c, nil
+}
+
+// dial 建立新的连接，并开始发送心跳
+func (p *pool) dial(address string) (*conn, error) {
+	var (
+		exportClient *export.Client
+		err          error
+	)
+
+	callback := &ReadyStateCallback{
+		Open:         p.options.onOpen,
+		Close:        p.options.onClose,
+		Error:        p.options.onError,
+		Reconnecting: p.options.onReconnecting,
+		Reconnected:  p.options.onReconnected,
+	}
+
+	if p.options.network == linker.NetworkTCP {
+		exportClient, err = export.NewClient(address, callback)
+	} else {
+		exportClient, err = export.NewUDPClient(address, callback)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.options.reconnect != nil {
+		exportClient.SetReconnectPolicy(*p.options.reconnect)
+	}
+
+	exportClient.SetUDPPayload(p.options.udpPayload)
+	exportClient.SetContentType(p.options.contentType)
+	exportClient.SetHeartbeatInterval(p.options.heartbeatInterval)
+	exportClient.SetPluginForPacketSender(p.options.pluginForPacketSender...)
+	exportClient.SetPluginForPacketReceiver(p.options.pluginForPacketReceiver...)
+	for k, v := range p.options.ext {
+		exportClient.SetRequestProperty(k, v)
+	}
+
+	c := &conn{Client: exportClient, address: address, lastUsed: time.Now().UnixNano()}
+
+	go p.keepalive(c)
+
+	return c, nil
+}
+
+// fill 补足地址上的最少连接数
+func (p *pool) fill(address string) error {
+	for {
+		select {
+		case <-p.done:
+			return nil
+		default:
+		}
+
+		p.mu.Lock()
+		e, ok := p.endpoints[address]
+		if !ok || len(e.conns)+e.dialing >= p.options.minConns {
+			p.mu.Unlock()
+			return nil
+		}
+
+		e.dialing++
+		p.mu.Unlock()
+
+		c, err := p.dial(address)
+
+		p.mu.Lock()
+		e.dialing--
+		if err == nil {
+			if p.endpoints[address] == e {
+				e.conns = append(e.conns, c)
+			} else {
+				p.mu.Unlock()
+				_ = c.Close()
+				return nil
+			}
+		}
+		p.mu.Unlock()
+
 		if err != nil {
-			return nil, fmt.Errorf("brpc error: %s\n", err.Error())
+			return err
+		}
+	}
+}
+
+// keepalive 定时发送心跳，连续失败超过限制或者空闲超时的连接从连接池中移除
+func (p *pool) keepalive(c *conn) {
+	for {
+		interval := c.HeartbeatInterval()
+		if interval <= 0 {
+			return
 		}
 
-		exportClient.SetUDPPayload(c.options.udpPayload)
-		exportClient.SetContentType(c.options.contentType)
-		exportClient.SetPluginForPacketSender(c.options.pluginForPacketSender...)
-		exportClient.SetPluginForPacketReceiver(c.options.pluginForPacketReceiver...)
-		for k, v := range c.options.ext {
-			exportClient.SetRequestProperty(k, v)
+		select {
+		case <-time.After(interval):
+		case <-p.done:
+			return
 		}
 
-		go func(ec *export.Client) {
-			ticker := time.NewTicker(time.Duration(interval) * time.Second)
-			for {
-				select {
-				case <-ticker.C:
-					err := ec.Ping(nil, cb)
-					if err != nil {
-						return
-					}
+		if c.GetReadyState() == export.CLOSED {
+			p.evict(c)
+			return
+		}
+
+		if err := p.ping(c, interval); err != nil {
+			if atomic.AddInt32(&c.failures, 1) >= int32(p.options.maxHeartbeatFailures) {
+				if p.options.onError != nil {
+					p.options.onError(err)
 				}
+
+				p.evict(c)
+				return
 			}
-		}(exportClient)
 
-		c.availableAddress <- address
+			continue
+		}
+
+		atomic.StoreInt32(&c.failures, 0)
+
+		if p.options.idleTimeout > 0 && c.Outstanding() == 0 && c.idle() > p.options.idleTimeout && p.shrink(c) {
+			return
+		}
+	}
+}
+
+// ping 发送心跳并等待结果
+func (p *pool) ping(c *conn, timeout time.Duration) error {
+	ch := make(chan error, 1)
+	err := c.Ping(nil, RequestStatusCallback{
+		Success: func(header, body []byte) {
+			ch <- nil
+		},
+		Error: func(code int, message string) {
+			ch <- linker.NewStatusError(code, message)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(timeout):
+		return ErrorHeartbeatTimeout
+	case <-p.done:
+		return ErrorPoolClosed
+	}
+}
+
+// evict 移除不健康的连接，并补足最少连接数
+func (p *pool) evict(c *conn) {
+	p.remove(c)
+	_ = c.Close()
+
+	go func() {
+		if err := p.fill(c.address); err != nil && p.options.onError != nil {
+			p.options.onError(err)
+		}
+	}()
+}
 
-		return exportClient, nil
+// shrink 关闭超过最少连接数的空闲连接
+func (p *pool) shrink(c *conn) bool {
+	p.mu.Lock()
+	e, ok := p.endpoints[c.address]
+	if !ok || len(e.conns) <= p.options.minConns {
+		p.mu.Unlock()
+		return false
 	}
+	p.mu.Unlock()
+
+	p.remove(c)
+	_ = c.Close()
+
+	return true
+}
+
+func (p *pool) remove(c *conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	close := func(v interface{}) error {
-		return v.(*export.Client).Close()
+	e, ok := p.endpoints[c.address]
+	if !ok {
+		return
 	}
 
-	ping := func(v interface{}) error {
-		return v.(*export.Client).Ping(nil, cb)
+	for i, v := range e.conns {
+		if v == c {
+			e.conns = append(e.conns[:i], e.conns[i+1:]...)
+			return
+		}
 	}
+}
+
+// stats 统计连接池状态，address为空时统计所有地址
+func (p *pool) stats(address string) Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var s Stats
+	for addr, e := range p.endpoints {
+		if address != "" && addr != address {
+			continue
+		}
+
+		for _, c := range e.conns {
+			s.Conns++
+			if c.Outstanding() > 0 {
+				s.InUse++
+			} else {
+				s.Idle++
+			}
+		}
 
-	pc := &pool.Config{
-		InitialCap: c.options.initialCap,
-		MaxCap:     c.options.maxCap,
-		Factory:    factory,
-		Close:      close,
-		Ping:       ping,
-		//连接最大空闲时间，超过该时间的连接 将会关闭，可避免空闲时连接EOF，自动失效的问题
-		IdleTimeout: c.options.idleTimeout,
+		s.Pending += p.pending[addr]
 	}
 
-	return pool.NewChannelPool(pc)
+	return s
+}
+
+func (p *pool) close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		_ = p.options.resolver.Close()
+
+		p.mu.Lock()
+		var list []*conn
+		for _, e := range p.endpoints {
+			list = append(list, e.conns...)
+			e.conns = nil
+		}
+		p.mu.Unlock()
+
+		for _, c := range list {
+			_ = c.Close()
+		}
+	})
+}
+
+func (c *conn) touch() {
+	atomic.StoreInt64(&c.lastUsed, time.Now().UnixNano())
+}
+
+func (c *conn) idle() time.Duration {
+	return time.Duration(time.Now().UnixNano() - atomic.LoadInt64(&c.lastUsed))
 }
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/export"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+func newTestServer(t *testing.T) (*linker.Server, string) {
+	router := linker.NewRouter()
+	router.Route("/sleep", linker.HandlerFunc(func(ctx linker.Context) {
+		time.Sleep(200 * time.Millisecond)
+		ctx.Success(nil)
+	}))
+	return servertest.Start(t, router)
+}
+
+func TestPoolMultiplexing(t *testing.T) {
+	_, address := newTestServer(t)
+
+	c, err := NewClient([]string{address}, MinConns(1), MaxConns(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var (
+		wg    sync.WaitGroup
+		n     = 20
+		start = time.Now()
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			session, err := c.Session()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			err = session.SyncSend("/sleep", nil, RequestStatusCallback{
+				Error: func(code int, message string) {
+					t.Errorf("request error: %d %s", code, message)
+				},
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if s := c.Stats(); s.Conns != 1 || s.InUse != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+
+	wg.Wait()
+
+	// 同一个连接上的请求并发处理
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("requests are not multiplexed: %s", d)
+	}
+
+	if s := c.Stats(); s.Conns != 1 || s.Idle != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestPoolEviction(t *testing.T) {
+	s, address := newTestServer(t)
+
+	c, err := NewClient([]string{address}, MinConns(2), HeartbeatInterval(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for deadline := time.Now().Add(3 * time.Second); s.Connections().Count() != 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("connections not accepted")
+		}
+	}
+
+	before := make(map[string]bool)
+	s.Connections().Range(func(conn *linker.Connection) bool {
+		before[conn.NodeID()] = true
+		return conn.Close() == nil
+	})
+
+	if len(before) != 2 {
+		t.Fatalf("unexpected connections: %d", len(before))
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	// 断开的连接被移除，并重新补足最少连接数
+	after := 0
+	s.Connections().Range(func(conn *linker.Connection) bool {
+		if !before[conn.NodeID()] {
+			after++
+		}
+
+		return true
+	})
+
+	if st := c.Stats(); st.Conns != 2 || after != 2 {
+		t.Fatalf("unexpected stats: %+v, new connections: %d", st, after)
+	}
+}
+
+func TestPoolClosedConn(t *testing.T) {
+	s, address := newTestServer(t)
+
+	c, err := NewClient([]string{address}, MinConns(1), MaxConns(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	session, err := c.Session()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for deadline := time.Now().Add(3 * time.Second); s.Connections().Count() != 1; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("connection not accepted")
+		}
+	}
+
+	s.Connections().Range(func(conn *linker.Connection) bool {
+		return conn.Close() == nil
+	})
+
+	for deadline := time.Now().Add(3 * time.Second); session.GetReadyState() != export.CLOSED; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("connection not closed")
+		}
+	}
+
+	// 没有重连策略时，已经关闭的连接不占用连接数上限，立即建立新的连接
+	next, err := c.Session()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if next == session || next.GetReadyState() != export.OPEN {
+		t.Fatal("closed connection reused")
+	}
+
+	if st := c.Stats(); st.Conns != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestPoolConns(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"conns", []Option{MinConns(2), MaxConns(3)}},
+		{"capacity", []Option{InitialCapacity(2), MaxCapacity(3)}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, address := newTestServer(t)
+
+			c, err := NewClient([]string{address}, append(tt.opts, MaxStreams(1))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			// 预先建立最少连接数
+			for deadline := time.Now().Add(3 * time.Second); c.Stats().Conns != 2; time.Sleep(10 * time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatalf("unexpected stats: %+v", c.Stats())
+				}
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					session, err := c.Session()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+
+					if err := session.SyncSend("/sleep", nil, RequestStatusCallback{}); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+
+			// 并发请求超过单个连接的上限时新建连接，但不超过最多连接数
+			time.Sleep(100 * time.Millisecond)
+			if s := c.Stats(); s.Conns != 3 {
+				t.Fatalf("unexpected stats: %+v", s)
+			}
+
+			wg.Wait()
+		})
+	}
+}
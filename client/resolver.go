@@ -0,0 +1,42 @@
+package client
+
+type (
+	// Address 解析得到的服务端地址
+	Address struct {
+		Addr   string
+		Weight int
+	}
+
+	// Resolver 服务端地址解析器，地址发生变化时通过channel推送最新的完整地址列表，
+	// 连接池根据地址列表的变化建立新的连接或者移除旧的连接，Close后channel需要被关闭
+	Resolver interface {
+		Resolve() (<-chan []Address, error)
+		Close() error
+	}
+
+	staticResolver struct {
+		addresses []Address
+	}
+)
+
+// NewStaticResolver 固定的地址列表
+func NewStaticResolver(address ...string) Resolver {
+	r := &staticResolver{}
+	for _, addr := range address {
+		r.addresses = append(r.addresses, Address{Addr: addr})
+	}
+
+	return r
+}
+
+func (r *staticResolver) Resolve() (<-chan []Address, error) {
+	ch := make(chan []Address, 1)
+	ch <- r.addresses
+	close(ch)
+
+	return ch, nil
+}
+
+func (r *staticResolver) Close() error {
+	return nil
+}
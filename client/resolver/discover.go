@@ -0,0 +1,105 @@
+package resolver
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wpajqz/linker/client"
+	"github.com/wpajqz/linker/discover"
+)
+
+type discoverResolver struct {
+	service     discover.IServicer
+	serviceType string
+	interval    time.Duration
+	changed     chan struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewDiscover 从etcd中发现服务类型为serviceType的地址，path为需要监听的路径，
+// 实例变化时立即推送新的地址，同时每隔interval检查一次地址是否发生变化
+func NewDiscover(service discover.IServicer, path, serviceType string, interval time.Duration) client.Resolver {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	r := &discoverResolver{
+		service:     service,
+		serviceType: serviceType,
+		interval:    interval,
+		changed:     make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+
+	service.OnEvent(func(event discover.Event) {
+		if event.ServiceType != serviceType {
+			return
+		}
+
+		select {
+		case r.changed <- struct{}{}:
+		default:
+		}
+	})
+	service.WatchNodes(path)
+
+	return r
+}
+
+func (r *discoverResolver) Resolve() (<-chan []client.Address, error) {
+	ch := make(chan []client.Address, 1)
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		var last []client.Address
+		for first := true; ; first = false {
+			addresses := r.addresses()
+			if first || !reflect.DeepEqual(addresses, last) {
+				last = addresses
+
+				select {
+				case ch <- addresses:
+				case <-r.done:
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-r.changed:
+			case <-r.done:
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (r *discoverResolver) addresses() []client.Address {
+	var list []client.Address
+	for _, info := range r.service.GetServices(r.serviceType) {
+		if info.Healthy() {
+			list = append(list, client.Address{Addr: info.GetValue(), Weight: info.Weight})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Addr < list[j].Addr })
+
+	return list
+}
+
+func (r *discoverResolver) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package resolver
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/wpajqz/linker/client"
+	"sigs.k8s.io/yaml"
+)
+
+type (
+	// fileAddress 文件中的地址，支持JSON和YAML格式
+	fileAddress struct {
+		Address string `json:"address"`
+		Weight  int    `json:"weight"`
+	}
+
+	fileResolver struct {
+		path      string
+		interval  time.Duration
+		modTime   time.Time
+		addresses []client.Address
+		done      chan struct{}
+		closeOnce sync.Once
+	}
+)
+
+// NewFile 从文件中读取地址列表，每隔interval检查文件是否被修改，修改后重新读取。
+// 文件内容为地址数组，例如 [{"address": "127.0.0.1:8080", "weight": 1}]，YAML格式同理
+func NewFile(path string, interval time.Duration) client.Resolver {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	return &fileResolver{path: path, interval: interval, done: make(chan struct{})}
+}
+
+func (r *fileResolver) Resolve() (<-chan []client.Address, error) {
+	if _, err := r.read(); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []client.Address, 1)
+	ch <- r.addresses
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// 文件格式错误时保留上一次的结果
+				changed, err := r.read()
+				if err != nil || !changed {
+					continue
+				}
+
+				select {
+				case ch <- r.addresses:
+				case <-r.done:
+					return
+				}
+			case <-r.done:
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// read 文件被修改时重新读取，返回地址列表是否发生变化
+func (r *fileResolver) read() (bool, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, err
+	}
+
+	if !r.modTime.IsZero() && info.ModTime().Equal(r.modTime) {
+		return false, nil
+	}
+
+	data, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return false, err
+	}
+
+	var list []fileAddress
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return false, err
+	}
+
+	addresses := make([]client.Address, 0, len(list))
+	for _, v := range list {
+		addresses = append(addresses, client.Address{Addr: v.Address, Weight: v.Weight})
+	}
+
+	r.modTime = info.ModTime()
+	if reflect.DeepEqual(addresses, r.addresses) {
+		return false, nil
+	}
+
+	r.addresses = addresses
+
+	return true, nil
+}
+
+func (r *fileResolver) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package resolver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker/client"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resolver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "address.yaml")
+	if err := ioutil.WriteFile(path, []byte("- address: 127.0.0.1:8080\n  weight: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewFile(path, 10*time.Millisecond)
+	defer r.Close()
+
+	ch, err := r.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list := <-ch; len(list) != 1 || list[0] != (client.Address{Addr: "127.0.0.1:8080", Weight: 2}) {
+		t.Fatalf("unexpected addresses: %v", list)
+	}
+
+	// JSON同样可以解析，修改时间需要变化才会重新读取
+	if err := ioutil.WriteFile(path, []byte(`[{"address": "127.0.0.1:8080"}, {"address": "127.0.0.1:8081"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Second)
+	_ = os.Chtimes(path, future, future)
+
+	select {
+	case list := <-ch:
+		if len(list) != 2 || list[1].Addr != "127.0.0.1:8081" {
+			t.Fatalf("unexpected addresses: %v", list)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("file change not detected")
+	}
+}
@@ -0,0 +1,107 @@
+package resolver
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/wpajqz/linker/client"
+	"github.com/wpajqz/linker/discover"
+)
+
+type registryResolver struct {
+	registry discover.Registry
+	service  string
+	filters  []discover.Filter
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewRegistry 通过Registry.Watch发现服务名为service的地址，实例变化时立即推送新的地址，
+// 只保留健康并且符合filters的实例，例如配合health.Checker的Filter排除检查失败的实例
+func NewRegistry(registry discover.Registry, service string, filters ...discover.Filter) client.Resolver {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &registryResolver{
+		registry: registry,
+		service:  service,
+		filters:  filters,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (r *registryResolver) Resolve() (<-chan []client.Address, error) {
+	instances, err := r.registry.Watch(r.ctx, r.service)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []client.Address, 1)
+
+	go func() {
+		defer close(ch)
+
+		var last []client.Address
+		for first := true; ; first = false {
+			var list []discover.Instance
+
+			select {
+			case l, ok := <-instances:
+				if !ok {
+					return
+				}
+				list = l
+			case <-r.ctx.Done():
+				return
+			}
+
+			addresses := r.addresses(list)
+			if !first && reflect.DeepEqual(addresses, last) {
+				continue
+			}
+			last = addresses
+
+			select {
+			case ch <- addresses:
+			case <-r.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (r *registryResolver) addresses(instances []discover.Instance) []client.Address {
+	var list []client.Address
+	for _, instance := range instances {
+		if info := instance.Info(); r.match(info) {
+			list = append(list, client.Address{Addr: info.GetValue(), Weight: info.Weight})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Addr < list[j].Addr })
+
+	return list
+}
+
+func (r *registryResolver) match(info discover.ServerInfo) bool {
+	if !info.Healthy() {
+		return false
+	}
+
+	for _, filter := range r.filters {
+		if !filter(info) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *registryResolver) Close() error {
+	r.cancel()
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package resolver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker/client"
+	"github.com/wpajqz/linker/discover"
+	"github.com/wpajqz/linker/discover/memory"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := memory.NewRegistry()
+	register := func(id, address string, metadata map[string]string) {
+		instance := discover.Instance{ID: id, Service: "api", Address: address, Metadata: metadata}
+		if err := registry.Register(context.Background(), instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	register("node1", "127.0.0.1:8081", map[string]string{discover.MetadataZone: "a", discover.MetadataWeight: "2"})
+	register("node2", "127.0.0.1:8082", map[string]string{discover.MetadataZone: "b"})
+	register("node3", "127.0.0.1:8083", map[string]string{discover.MetadataZone: "a", discover.MetadataHealth: string(discover.HealthCritical)})
+
+	r := NewRegistry(registry, "api", discover.InZone("a"))
+
+	ch, err := r.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(want []client.Address) {
+		t.Helper()
+
+		select {
+		case list := <-ch:
+			if !reflect.DeepEqual(list, want) {
+				t.Fatalf("unexpected addresses: %v", list)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("addresses not resolved")
+		}
+	}
+
+	// 不健康和不符合条件的实例被排除
+	expect([]client.Address{{Addr: "127.0.0.1:8081", Weight: 2}})
+
+	register("node4", "127.0.0.1:8084", map[string]string{discover.MetadataZone: "a"})
+	expect([]client.Address{{Addr: "127.0.0.1:8081", Weight: 2}, {Addr: "127.0.0.1:8084"}})
+
+	if err := registry.Deregister(context.Background(), discover.Instance{ID: "node1", Service: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	expect([]client.Address{{Addr: "127.0.0.1:8084"}})
+
+	// 关闭以后channel被关闭
+	_ = r.Close()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("channel not closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed")
+	}
+}
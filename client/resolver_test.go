@@ -0,0 +1,55 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+type chanResolver chan []Address
+
+func (r chanResolver) Resolve() (<-chan []Address, error) {
+	return r, nil
+}
+
+func (r chanResolver) Close() error {
+	return nil
+}
+
+func TestResolverUpdate(t *testing.T) {
+	balancerAddresses := startWhoamiServers(t, 2)
+
+	r := make(chanResolver, 1)
+	r <- []Address{{Addr: balancerAddresses[0]}}
+
+	c, err := NewClient(nil, WithResolver(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if address := whoami(t, c); address != balancerAddresses[0] {
+		t.Fatalf("unexpected address: %s", address)
+	}
+
+	// 新增地址以后建立连接
+	r <- []Address{{Addr: balancerAddresses[0]}, {Addr: balancerAddresses[1]}}
+	time.Sleep(200 * time.Millisecond)
+
+	if s := c.AddressStats(balancerAddresses[1]); s.Conns != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+
+	// 移除地址以后关闭连接，请求不再发往该地址
+	r <- []Address{{Addr: balancerAddresses[1]}}
+	time.Sleep(200 * time.Millisecond)
+
+	if s := c.AddressStats(balancerAddresses[0]); s.Conns != 0 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+
+	for i := 0; i < 5; i++ {
+		if address := whoami(t, c); address != balancerAddresses[1] {
+			t.Fatalf("unexpected address: %s", address)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/wpajqz/linker"
+)
+
+// RetryPolicy 请求失败时的重试策略，重试间隔按指数退避增长
+type RetryPolicy struct {
+	MaxAttempts    int           // 最多请求次数，包含第一次请求
+	InitialBackoff time.Duration // 第一次重试前的等待时间
+	MaxBackoff     time.Duration // 重试间隔上限
+	Multiplier     float64       // 每次重试后间隔的增长倍数
+	Jitter         float64       // 随机抖动比例，取值0~1
+	RetryableCodes []int         // 可以重试的状态码，为空时使用DefaultRetryableCodes
+}
+
+// DefaultRetryableCodes 默认可以重试的状态码
+var DefaultRetryableCodes = []int{
+	linker.StatusTooManyRequests,
+	linker.StatusBadGateway,
+	linker.StatusServiceUnavailable,
+	linker.StatusGatewayTimeout,
+}
+
+// backoff 计算第attempt次重试前需要等待的时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		d += d * math.Min(p.Jitter, 1) * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(math.Max(d, 0))
+}
+
+// retryable 服务端返回可以重试的状态码，或者请求没有发送到服务端时可以重试，
+// 连接在响应之前断开时返回StatusServiceUnavailable，解码失败、熔断等其他错误不重试
+func (p RetryPolicy) retryable(err error) bool {
+	switch err {
+	case ErrorNoAvailableAddress, ErrorNoAvailableConn:
+		return true
+	}
+
+	if _, ok := err.(*TransportError); ok {
+		return true
+	}
+
+	se, ok := err.(*StatusError)
+	if !ok {
+		return false
+	}
+
+	codes := p.RetryableCodes
+	if len(codes) == 0 {
+		codes = DefaultRetryableCodes
+	}
+
+	for _, code := range codes {
+		if se.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retry 按照请求类型对应的重试策略重试，第一次请求时生成幂等键，重试时保持不变
+func (c *Client) retry(ctx context.Context, operator string, req, resp interface{}, invoker Invoker) error {
+	policy, ok := c.options.retries[operator]
+	if !ok {
+		policy, ok = c.options.retries[""]
+	}
+
+	if !ok || policy.MaxAttempts <= 1 {
+		return invoker(ctx, operator, req, resp)
+	}
+
+	if Properties(ctx)[linker.IdempotencyKeyProperty] == "" {
+		ctx = WithProperty(ctx, linker.IdempotencyKeyProperty, uuid.NewV4().String())
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := invoker(ctx, operator, req, resp)
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(policy.backoff(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+func TestRetry(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+
+	router := linker.NewRouter()
+	router.Route("/flaky", linker.HandlerFunc(func(ctx linker.Context) {
+		mu.Lock()
+		keys = append(keys, ctx.GetRequestProperty(linker.IdempotencyKeyProperty))
+		n := len(keys)
+		mu.Unlock()
+
+		if n < 3 {
+			ctx.Error(linker.StatusServiceUnavailable, "unavailable")
+		}
+
+		ctx.Success(n)
+	}))
+	router.Route("/forbidden", linker.HandlerFunc(func(ctx linker.Context) {
+		mu.Lock()
+		keys = append(keys, ctx.GetRequestProperty(linker.IdempotencyKeyProperty))
+		mu.Unlock()
+
+		ctx.Error(linker.StatusForbidden, "forbidden")
+	}))
+	router.Route("/text", linker.HandlerFunc(func(ctx linker.Context) {
+		mu.Lock()
+		keys = append(keys, ctx.GetRequestProperty(linker.IdempotencyKeyProperty))
+		mu.Unlock()
+
+		ctx.Success("text")
+	}))
+	_, address := servertest.Start(t, router)
+
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}
+	c, err := NewClient([]string{address}, Retry(policy, "/flaky", "/forbidden", "/text"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var n int
+	if err := c.Invoke(context.Background(), "/flaky", nil, &n); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重试时使用相同的幂等键
+	mu.Lock()
+	if n != 3 || len(keys) != 3 || keys[0] == "" || keys[0] != keys[1] || keys[1] != keys[2] {
+		t.Fatalf("unexpected result: %d %v", n, keys)
+	}
+	keys = nil
+	mu.Unlock()
+
+	// 不可重试的状态码直接返回
+	err = c.Invoke(context.Background(), "/forbidden", nil, nil)
+	if se, ok := err.(*StatusError); !ok || se.Code != linker.StatusForbidden {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	if len(keys) != 1 {
+		t.Fatalf("unexpected attempts: %d", len(keys))
+	}
+	keys = nil
+	mu.Unlock()
+
+	// 请求已经处理成功，响应解码失败时不重试
+	if err := c.Invoke(context.Background(), "/text", nil, &n); err == nil {
+		t.Fatal("expected decode error")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 1 {
+		t.Fatalf("unexpected attempts: %d", len(keys))
+	}
+}
@@ -0,0 +1,332 @@
+package linker
+
+import (
+	"context"
+	"hash/crc32"
+	"net"
+	"sync"
+	"time"
+)
+
+type (
+	// Connection 服务端维护的客户端长连接
+	Connection struct {
+		nodeID        string
+		network       string
+		local, remote net.Addr
+		createdAt     time.Time
+		options       Options
+		writer        *connWriter
+		closer        func() error
+		keys          map[string]struct{}
+		rooms         map[string]struct{}
+		connections   *Connections
+		values        sync.Map
+		lastSeen      int64
+		heartbeat     int64
+		requests      sync.Map
+		limiter       limiter
+	}
+
+	// Connections 当前节点上所有长连接的注册表，可以通过nodeID或者自定义key查找连接
+	Connections struct {
+		mu       sync.RWMutex
+		nodes    map[string]*Connection
+		keys     map[string]map[string]*Connection
+		rooms    map[string]map[string]*Connection
+		presence *Presence
+	}
+)
+
+func newConnection(id, network string, local, remote net.Addr, options Options, writer *connWriter, closer func() error) *Connection {
+	now := time.Now()
+
+	return &Connection{
+		nodeID:    id,
+		network:   network,
+		local:     local,
+		remote:    remote,
+		createdAt: now,
+		options:   options,
+		writer:    writer,
+		closer:    closer,
+		keys:      make(map[string]struct{}),
+		rooms:     make(map[string]struct{}),
+		lastSeen:  now.UnixNano(),
+		heartbeat: int64(options.heartbeatInterval),
+		limiter:   newLimiter(options.maxConcurrentPerConn),
+	}
+}
+
+func (c *Connection) NodeID() string {
+	return c.nodeID
+}
+
+func (c *Connection) Network() string {
+	return c.network
+}
+
+func (c *Connection) LocalAddr() string {
+	return c.local.String()
+}
+
+func (c *Connection) RemoteAddr() string {
+	return c.remote.String()
+}
+
+func (c *Connection) CreatedAt() time.Time {
+	return c.createdAt
+}
+
+// track 记录正在处理的请求，返回的context在收到取消帧或者请求处理完成时被取消
+func (c *Connection) track(sequence int64) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.requests.Store(sequence, cancel)
+
+	return ctx
+}
+
+func (c *Connection) untrack(sequence int64) {
+	c.cancel(sequence)
+	c.requests.Delete(sequence)
+}
+
+// cancel 通知请求已经被客户端取消
+func (c *Connection) cancel(sequence int64) {
+	if v, ok := c.requests.Load(sequence); ok {
+		v.(context.CancelFunc)()
+	}
+}
+
+// Set 保存连接范围内的数据，同一连接上的所有请求都可以获取
+func (c *Connection) Set(key string, value interface{}) {
+	c.values.Store(key, value)
+}
+
+// Get 获取连接范围内保存的数据
+func (c *Connection) Get(key string) interface{} {
+	v, _ := c.values.Load(key)
+	return v
+}
+
+// 向客户端推送数据
+func (c *Connection) Write(operator string, body []byte) (int, error) {
+	p, err := NewPacket(crc32.ChecksumIEEE([]byte(operator)), 0, nil, body, c.options.pluginForPacketSender)
+	if err != nil {
+		return 0, err
+	}
+
+	b := p.Bytes()
+	if err := c.writer.Write(b); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// 断开连接，连接的清理工作在连接的销毁流程中完成
+func (c *Connection) Close() error {
+	return c.closer()
+}
+
+// drain 写出队列中已经提交的数据包以后断开连接，ctx结束时不再等待
+func (c *Connection) drain(ctx context.Context) {
+	if c.writer != nil {
+		flushed := make(chan struct{})
+		go func() {
+			c.writer.Close()
+			close(flushed)
+		}()
+
+		select {
+		case <-flushed:
+		case <-ctx.Done():
+		}
+	}
+
+	_ = c.closer()
+}
+
+func newConnections(presence *Presence) *Connections {
+	return &Connections{
+		nodes:    make(map[string]*Connection),
+		keys:     make(map[string]map[string]*Connection),
+		rooms:    make(map[string]map[string]*Connection),
+		presence: presence,
+	}
+}
+
+func (cs *Connections) add(c *Connection) {
+	cs.mu.Lock()
+	c.connections = cs
+	cs.nodes[c.nodeID] = c
+	cs.mu.Unlock()
+}
+
+func (cs *Connections) remove(nodeID string) {
+	cs.mu.Lock()
+
+	c, ok := cs.nodes[nodeID]
+	if !ok {
+		cs.mu.Unlock()
+		return
+	}
+
+	var keys []string
+	for key := range c.keys {
+		keys = append(keys, key)
+		cs.unbind(c, key)
+	}
+
+	for room := range c.rooms {
+		cs.leaveRoom(c, room)
+	}
+
+	delete(cs.nodes, nodeID)
+	cs.mu.Unlock()
+
+	for _, key := range keys {
+		_ = cs.presence.leave(key, nodeID)
+	}
+}
+
+// Get 根据nodeID获取连接
+func (cs *Connections) Get(nodeID string) (*Connection, bool) {
+	cs.mu.RLock()
+	c, ok := cs.nodes[nodeID]
+	cs.mu.RUnlock()
+
+	return c, ok
+}
+
+// Bind 给连接关联自定义key，例如用户ID，同一个key可以关联多个连接，key同时作为在线状态的身份标识
+func (cs *Connections) Bind(nodeID, key string) error {
+	cs.mu.Lock()
+
+	c, ok := cs.nodes[nodeID]
+	if !ok {
+		cs.mu.Unlock()
+		return ErrorConnectionNotFound
+	}
+
+	if _, ok := c.keys[key]; ok {
+		cs.mu.Unlock()
+		return nil
+	}
+
+	if cs.keys[key] == nil {
+		cs.keys[key] = make(map[string]*Connection)
+	}
+
+	cs.keys[key][nodeID] = c
+	c.keys[key] = struct{}{}
+	cs.mu.Unlock()
+
+	return cs.presence.join(key, nodeID)
+}
+
+// UnBind 解除连接和自定义key的关联
+func (cs *Connections) UnBind(nodeID, key string) error {
+	cs.mu.Lock()
+
+	c, ok := cs.nodes[nodeID]
+	if !ok {
+		cs.mu.Unlock()
+		return ErrorConnectionNotFound
+	}
+
+	if _, ok := c.keys[key]; !ok {
+		cs.mu.Unlock()
+		return nil
+	}
+
+	cs.unbind(c, key)
+	cs.mu.Unlock()
+
+	return cs.presence.leave(key, nodeID)
+}
+
+func (cs *Connections) unbind(c *Connection, key string) {
+	delete(c.keys, key)
+
+	if m, ok := cs.keys[key]; ok {
+		delete(m, c.nodeID)
+		if len(m) == 0 {
+			delete(cs.keys, key)
+		}
+	}
+}
+
+// Find 根据自定义key获取关联的所有连接
+func (cs *Connections) Find(key string) []*Connection {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	var list []*Connection
+	for _, c := range cs.keys[key] {
+		list = append(list, c)
+	}
+
+	return list
+}
+
+// Keys 获取连接关联的所有自定义key
+func (cs *Connections) Keys(nodeID string) []string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	var list []string
+	if c, ok := cs.nodes[nodeID]; ok {
+		for key := range c.keys {
+			list = append(list, key)
+		}
+	}
+
+	return list
+}
+
+// Range 遍历所有连接，fn返回false时停止遍历
+func (cs *Connections) Range(fn func(c *Connection) bool) {
+	cs.mu.RLock()
+	list := make([]*Connection, 0, len(cs.nodes))
+	for _, c := range cs.nodes {
+		list = append(list, c)
+	}
+	cs.mu.RUnlock()
+
+	for _, c := range list {
+		if !fn(c) {
+			return
+		}
+	}
+}
+
+// Count 当前连接数
+func (cs *Connections) Count() int {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	return len(cs.nodes)
+}
+
+// Send 向指定连接推送数据
+func (cs *Connections) Send(nodeID, operator string, body []byte) error {
+	c, ok := cs.Get(nodeID)
+	if !ok {
+		return ErrorConnectionNotFound
+	}
+
+	_, err := c.Write(operator, body)
+
+	return err
+}
+
+// Close 断开指定连接
+func (cs *Connections) Close(nodeID string) error {
+	c, ok := cs.Get(nodeID)
+	if !ok {
+		return ErrorConnectionNotFound
+	}
+
+	return c.Close()
+}
@@ -0,0 +1,203 @@
+package linker_test
+
+import (
+	"hash/crc32"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/export"
+	"github.com/wpajqz/linker/codec"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+// waitCount 等待服务端的连接数达到n
+func waitCount(t *testing.T, s *linker.Server, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for s.Connections().Count() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected connections: %d", s.Connections().Count())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConnections(t *testing.T) {
+	s, address := servertest.Start(t, linker.NewRouter())
+
+	c, err := export.NewClient(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	received := make(chan string, 1)
+	err = c.AddMessageListener("/notify", export.HandlerFunc(func(header, body []byte) {
+		received <- string(body)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitCount(t, s, 1)
+
+	cs := s.Connections()
+
+	var nodeID string
+	cs.Range(func(conn *linker.Connection) bool {
+		nodeID = conn.NodeID()
+		return false
+	})
+
+	if err := cs.Bind(nodeID, "u1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cs.Bind("unknown", "u1"); err != linker.ErrorConnectionNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found := cs.Find("u1")
+	if len(found) != 1 || found[0].NodeID() != nodeID {
+		t.Fatalf("unexpected connections: %v", found)
+	}
+
+	if keys := cs.Keys(nodeID); len(keys) != 1 || keys[0] != "u1" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	if err := cs.Send(nodeID, "/notify", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg != "hello" {
+			t.Fatalf("unexpected message: %s", msg)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+
+	if err := cs.Send("unknown", "/notify", nil); err != linker.ErrorConnectionNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := cs.UnBind(nodeID, "u1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if found := cs.Find("u1"); len(found) != 0 {
+		t.Fatalf("unexpected connections: %v", found)
+	}
+
+	// 连接断开以后解除所有关联
+	if err := cs.Bind(nodeID, "u2"); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = c.Close()
+	waitCount(t, s, 0)
+
+	if found := cs.Find("u2"); len(found) != 0 {
+		t.Fatalf("unexpected connections: %v", found)
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	failed := make(chan error, 1)
+	router := linker.NewRouter()
+	router.Route("/flood", linker.HandlerFunc(func(ctx linker.Context) {
+		data := make([]byte, 1<<20)
+		for i := 0; i < 64; i++ {
+			if _, err := ctx.Write("/flood", data); err != nil {
+				failed <- err
+				return
+			}
+		}
+	}))
+
+	_, address := servertest.Start(t, router, linker.WriteTimeout(100*time.Millisecond), linker.WriteQueueSize(1))
+
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	p, err := linker.NewPacket(crc32.ChecksumIEEE([]byte("/flood")), 1, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Write(p.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 客户端不读取数据，写出超时以后服务端断开连接，等待写队列的请求不会一直阻塞
+	select {
+	case err := <-failed:
+		if err != linker.ErrorConnectionClosed {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("write should fail after the write timeout")
+	}
+}
+
+func TestOnClose(t *testing.T) {
+	type result struct {
+		operator uint32
+		value    string
+	}
+
+	closed := make(chan result, 1)
+	router := linker.NewRouter()
+	router.Route("/set", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Set("value", "request")
+		ctx.Connection().Set("requested", true)
+		ctx.Success(nil)
+	}))
+
+	_, address := servertest.Start(t, router,
+		linker.WithOnOpen(linker.HandlerFunc(func(ctx linker.Context) {
+			ctx.Set("value", "open")
+		})),
+		linker.WithOnClose(linker.HandlerFunc(func(ctx linker.Context) {
+			if ctx.Connection().Get("requested") == nil {
+				return
+			}
+
+			closed <- result{ctx.Operator(), ctx.GetString("value")}
+		})),
+	)
+
+	c, err := export.NewClient(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.SetContentType(codec.JSON)
+
+	code := make(chan int, 1)
+	if err := c.SyncSend("/set", nil, resultCallback{code}); err != nil {
+		t.Fatal(err)
+	}
+	<-code
+
+	_ = c.Close()
+
+	// 连接断开时使用连接的context，而不是最后一个请求的context
+	select {
+	case r := <-closed:
+		if r.operator != 0 || r.value != "open" {
+			t.Fatalf("unexpected context: %+v", r)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for close")
+	}
+}
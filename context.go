@@ -9,6 +9,9 @@ import (
 	"github.com/wpajqz/linker/codec"
 )
 
+// 请求的幂等键，客户端重试时保持不变，服务端据此识别重复的请求
+const IdempotencyKeyProperty = "idempotency-key"
+
 type (
 	Context interface {
 		Set(key string, value interface{})
@@ -39,11 +42,21 @@ type (
 		Subscribe(topic string, process func([]byte)) error
 		UnSubscribe(topic string) error
 		UnSubscribeAll() error
+		Bind(userID string) error
+		UnBind(userID string) error
+		Join(room string) error
+		Leave(room string) error
 		Version() string
+		Operator() uint32
+		NodeID() string
+		Connection() *Connection
+		Ctx() context.Context
 	}
 
 	common struct {
 		options           Options
+		connection        *Connection
+		cancelCtx         context.Context
 		operateType       uint32
 		sequence          int64
 		body              []byte
@@ -54,6 +67,16 @@ type (
 	}
 )
 
+// requestContext 取消信号来自请求，值来自Set保存的数据
+type requestContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c requestContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
 // Set is used to store a new key/value pair exclusively for this context.
 func (dc *common) Set(key string, value interface{}) {
 	dc.Context = context.WithValue(dc.Context, key, value)
@@ -164,6 +187,16 @@ func (dc *common) GetStringMap(key string) (sm map[string]interface{}) {
 	return
 }
 
+// Ctx 请求的context，客户端发送取消帧时被取消，Err返回context.Canceled，值为Set保存的数据。
+// 使用InOrder或者OverloadQueue时，读取循环等待请求处理期间无法读取取消帧，请求不会被取消
+func (dc *common) Ctx() context.Context {
+	if dc.cancelCtx == nil {
+		return dc.Context
+	}
+
+	return requestContext{Context: dc.cancelCtx, values: dc.Context}
+}
+
 func (dc *common) ParseParam(data interface{}) error {
 	r, err := codec.NewCoder(dc.options.contentType)
 	if err != nil {
@@ -234,7 +267,14 @@ func (dc *common) SetResponseProperty(key, value string) {
 	new := []byte("")
 
 	dc.Response.Header = bytes.ReplaceAll(dc.Response.Header, old, new)
-	dc.Response.Header = append(dc.Response.Header, []byte(key+"="+value+";")...)
+	dc.Response.Header = append(dc.Response.Header, []byte(key+"="+propertyValue(value)+";")...)
+}
+
+// header的格式为k=v;，属性值中的;和=替换为,和:，避免截断属性值或者注入其他属性
+var propertyReplacer = strings.NewReplacer(";", ",", "=", ":")
+
+func propertyValue(value string) string {
+	return propertyReplacer.Replace(value)
 }
 
 func (dc *common) GetResponseProperty(key string) string {
@@ -260,3 +300,18 @@ func (dc *common) RawBody() []byte {
 func (dc *common) Version() string {
 	return dc.GetRequestProperty("v")
 }
+
+// Operator 获取请求的帧类型
+func (dc *common) Operator() uint32 {
+	return dc.operateType
+}
+
+// NodeID 获取请求所在连接的唯一标识
+func (dc *common) NodeID() string {
+	return dc.GetString(nodeID)
+}
+
+// Connection 获取请求所在的长连接，udp请求返回nil
+func (dc *common) Connection() *Connection {
+	return dc.connection
+}
@@ -6,7 +6,6 @@ import (
 	"runtime"
 	"strconv"
 
-	"github.com/gorilla/websocket"
 	"github.com/wpajqz/linker/codec"
 )
 
@@ -49,7 +48,7 @@ func (c *ContextWebsocket) Success(body interface{}) {
 		panic(err)
 	}
 
-	_ = c.Conn.WriteMessage(websocket.BinaryMessage, p.Bytes())
+	_ = c.Conn.WriteMessage(p.Bytes())
 
 	runtime.Goexit()
 }
@@ -65,7 +64,7 @@ func (c *ContextWebsocket) Error(code int, message string) {
 		panic(err)
 	}
 
-	_ = c.Conn.WriteMessage(websocket.BinaryMessage, p.Bytes())
+	_ = c.Conn.WriteMessage(p.Bytes())
 
 	runtime.Goexit()
 }
@@ -77,7 +76,7 @@ func (c *ContextWebsocket) Write(operator string, body []byte) (int, error) {
 		return 0, err
 	}
 
-	return 0, c.Conn.WriteMessage(websocket.BinaryMessage, p.Bytes())
+	return 0, c.Conn.WriteMessage(p.Bytes())
 }
 
 func (c *ContextWebsocket) LocalAddr() string {
@@ -48,7 +48,7 @@ func (c *ContextTcp) Success(body interface{}) {
 		panic(err)
 	}
 
-	_, _ = c.Conn.Write(p.Bytes())
+	_, _ = c.write(p.Bytes())
 
 	runtime.Goexit()
 }
@@ -64,7 +64,7 @@ func (c *ContextTcp) Error(code int, message string) {
 		panic(err)
 	}
 
-	_, _ = c.Conn.Write(p.Bytes())
+	_, _ = c.write(p.Bytes())
 
 	runtime.Goexit()
 }
@@ -76,7 +76,20 @@ func (c *ContextTcp) Write(operator string, body []byte) (int, error) {
 		return 0, err
 	}
 
-	return c.Conn.Write(p.Bytes())
+	return c.write(p.Bytes())
+}
+
+// 数据包交给连接的写协程发送，保证同一连接上的写入顺序
+func (c *ContextTcp) write(b []byte) (int, error) {
+	if c.connection == nil {
+		return c.Conn.Write(b)
+	}
+
+	if err := c.connection.writer.Write(b); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
 }
 
 func (c *ContextTcp) LocalAddr() string {
@@ -87,5 +87,5 @@ func (c *ContextUdp) LocalAddr() string {
 }
 
 func (c *ContextUdp) RemoteAddr() string {
-	return c.Conn.RemoteAddr().String()
+	return c.remote.String()
 }
@@ -4,8 +4,13 @@ import "errors"
 
 // error
 var (
-	ErrorServicePathNil   = errors.New("service path nil")
-	ErrorServiceTypeNil   = errors.New("service type nil")
-	ErrorServiceNameNil   = errors.New("service name nil")
-	ErrorServiceIPInfoNil = errors.New("service ip info nil")
+	ErrorServicePathNil     = errors.New("service path nil")
+	ErrorServiceTypeNil     = errors.New("service type nil")
+	ErrorServiceNameNil     = errors.New("service name nil")
+	ErrorServiceIPInfoNil   = errors.New("service ip info nil")
+	ErrorInstanceIDNil      = errors.New("instance id nil")
+	ErrorReadOnly           = errors.New("registry is read only")
+	ErrorServiceNotFound    = errors.New("service not found")
+	ErrorNoMatchingInstance = errors.New("no matching instance")
+	ErrorWatchClosed        = errors.New("watch channel closed")
 )
@@ -0,0 +1,313 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wpajqz/linker/discover"
+	"go.etcd.io/etcd/clientv3"
+)
+
+type (
+	// Registry 基于etcd的服务注册表，实例绑定租约，进程退出后租约过期实例自动删除
+	Registry struct {
+		client  *clientv3.Client
+		options Options
+		mu      sync.Mutex
+		leases  map[string]*lease
+	}
+
+	lease struct {
+		id     clientv3.LeaseID
+		value  string
+		cancel context.CancelFunc
+	}
+)
+
+func NewRegistry(endpoints []string, opts ...Option) (*Registry, error) {
+	options := Options{
+		prefix:      "/linker/services",
+		ttl:         10,
+		dialTimeout: 3 * time.Second,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	options.prefix = strings.TrimSuffix(options.prefix, "/")
+
+	client, err := clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: options.dialTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Registry{client: client, options: options, leases: make(map[string]*lease)}, nil
+}
+
+func (r *Registry) Register(ctx context.Context, instance discover.Instance) error {
+	if err := instance.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return err
+	}
+
+	key := r.key(instance.Service, instance.ID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 已经注册过的实例使用原来的租约更新信息
+	if l, ok := r.leases[key]; ok {
+		if _, err := r.client.Put(ctx, key, string(data), clientv3.WithLease(l.id)); err != nil {
+			return err
+		}
+
+		l.value = string(data)
+
+		return nil
+	}
+
+	l, err := r.grant(ctx, key, string(data))
+	if err != nil {
+		return err
+	}
+
+	r.leases[key] = l
+
+	return nil
+}
+
+// grant 创建租约并写入实例信息，租约续约失败时（例如etcd重启）重新注册，直到实例被注销
+func (r *Registry) grant(ctx context.Context, key, value string) (*lease, error) {
+	resp, err := r.client.Grant(ctx, r.options.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.client.Put(ctx, key, value, clientv3.WithLease(resp.ID)); err != nil {
+		return nil, err
+	}
+
+	kctx, cancel := context.WithCancel(context.Background())
+	ch, err := r.client.KeepAlive(kctx, resp.ID)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	l := &lease{id: resp.ID, value: value, cancel: cancel}
+
+	go func() {
+		for range ch {
+		}
+
+		for {
+			select {
+			case <-kctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+
+			r.mu.Lock()
+			current, value := r.leases[key], l.value
+			r.mu.Unlock()
+
+			if current != l {
+				return
+			}
+
+			// 重新注册时不持有锁，请求有超时时间，etcd不可用时不会阻塞注册和注销
+			gctx, gcancel := context.WithTimeout(kctx, r.options.dialTimeout)
+			nl, err := r.grant(gctx, key, value)
+			gcancel()
+			if err != nil {
+				continue
+			}
+
+			// 重新注册期间实例被注销或者更新时丢弃新的租约
+			r.mu.Lock()
+			swapped := r.leases[key] == l && l.value == value
+			if swapped {
+				r.leases[key] = nl
+			}
+			r.mu.Unlock()
+
+			if !swapped {
+				r.revoke(nl)
+				continue
+			}
+
+			cancel()
+			return
+		}
+	}()
+
+	return l, nil
+}
+
+func (r *Registry) Deregister(ctx context.Context, instance discover.Instance) error {
+	key := r.key(instance.Service, instance.ID)
+
+	r.mu.Lock()
+	l, ok := r.leases[key]
+	delete(r.leases, key)
+	r.mu.Unlock()
+
+	if ok {
+		l.cancel()
+		if _, err := r.client.Revoke(ctx, l.id); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.client.Delete(ctx, key)
+
+	return err
+}
+
+func (r *Registry) List(ctx context.Context, service string) ([]discover.Instance, error) {
+	resp, err := r.client.Get(ctx, r.key(service, ""), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]discover.Instance, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var instance discover.Instance
+		if err := json.Unmarshal(kv.Value, &instance); err != nil {
+			continue
+		}
+
+		list = append(list, instance)
+	}
+
+	discover.SortInstances(list)
+
+	return list, nil
+}
+
+func (r *Registry) Watch(ctx context.Context, service string) (<-chan []discover.Instance, error) {
+	prefix := r.key(service, "")
+
+	resp, err := r.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []discover.Instance, 1)
+	instances := r.snapshot(resp)
+	discover.Notify(ch, list(instances))
+
+	go func() {
+		defer close(ch)
+
+		revision := resp.Header.Revision + 1
+		for {
+			wctx, wcancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+			wch := r.client.Watch(wctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(revision))
+			for wresp := range wch {
+				// 历史版本被压缩或者连接出错时重新获取全部实例
+				if wresp.CompactRevision != 0 || wresp.Err() != nil {
+					break
+				}
+
+				for _, ev := range wresp.Events {
+					switch ev.Type {
+					case clientv3.EventTypePut:
+						var instance discover.Instance
+						if err := json.Unmarshal(ev.Kv.Value, &instance); err == nil {
+							instances[string(ev.Kv.Key)] = instance
+						}
+					case clientv3.EventTypeDelete:
+						delete(instances, string(ev.Kv.Key))
+					}
+				}
+
+				revision = wresp.Header.Revision + 1
+				discover.Notify(ch, list(instances))
+			}
+			wcancel()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				resp, err := r.client.Get(ctx, prefix, clientv3.WithPrefix())
+				if err == nil {
+					instances = r.snapshot(resp)
+					revision = resp.Header.Revision + 1
+					discover.Notify(ch, list(instances))
+					break
+				}
+
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Close 注销所有通过该注册表注册的实例并关闭客户端
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	leases := r.leases
+	r.leases = make(map[string]*lease)
+	r.mu.Unlock()
+
+	for _, l := range leases {
+		r.revoke(l)
+	}
+
+	return r.client.Close()
+}
+
+// revoke 停止续约并撤销租约，租约绑定的实例随之删除
+func (r *Registry) revoke(l *lease) {
+	l.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.options.dialTimeout)
+	_, _ = r.client.Revoke(ctx, l.id)
+	cancel()
+}
+
+func (r *Registry) key(service, id string) string {
+	return r.options.prefix + "/" + service + "/" + id
+}
+
+func (r *Registry) snapshot(resp *clientv3.GetResponse) map[string]discover.Instance {
+	instances := make(map[string]discover.Instance, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var instance discover.Instance
+		if err := json.Unmarshal(kv.Value, &instance); err == nil {
+			instances[string(kv.Key)] = instance
+		}
+	}
+
+	return instances
+}
+
+func list(instances map[string]discover.Instance) []discover.Instance {
+	result := make([]discover.Instance, 0, len(instances))
+	for _, instance := range instances {
+		result = append(result, instance)
+	}
+
+	discover.SortInstances(result)
+
+	return result
+}
@@ -0,0 +1,95 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker/discover"
+	"github.com/wpajqz/linker/discover/internal/etcdtest"
+	"github.com/wpajqz/linker/discover/registrytest"
+)
+
+var endpoint string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "etcd")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	e, address, err := etcdtest.Start(dir)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	endpoint = address
+	code := m.Run()
+
+	e.Close()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestRegistry(t *testing.T) {
+	registrytest.Run(t, func(t *testing.T) discover.Registry {
+		// 每个测试使用不同的前缀，互不影响
+		r, err := NewRegistry([]string{endpoint}, Prefix("/test/"+strings.Replace(t.Name(), "/", "_", -1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return r
+	})
+}
+
+func TestDeregisterUnavailable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e, address, err := etcdtest.Start(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRegistry([]string{address}, TTL(1), DialTimeout(500*time.Millisecond))
+	if err != nil {
+		e.Close()
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	instance := discover.Instance{ID: "1", Service: "test", Address: "127.0.0.1:8080"}
+	if err := r.Register(context.Background(), instance); err != nil {
+		e.Close()
+		t.Fatal(err)
+	}
+
+	// etcd停止以后租约过期，等待后台开始重新注册
+	e.Close()
+	time.Sleep(3 * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		_ = r.Deregister(ctx, instance)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("deregister blocked while etcd is unavailable")
+	}
+}
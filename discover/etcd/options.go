@@ -0,0 +1,33 @@
+package etcd
+
+import "time"
+
+type (
+	Options struct {
+		prefix      string
+		ttl         int64
+		dialTimeout time.Duration
+	}
+
+	Option func(*Options)
+)
+
+// Prefix 实例在etcd中保存的路径前缀
+func Prefix(prefix string) Option {
+	return func(o *Options) {
+		o.prefix = prefix
+	}
+}
+
+// TTL 实例租约的有效期，单位s，注册后会自动续约
+func TTL(ttl int64) Option {
+	return func(o *Options) {
+		o.ttl = ttl
+	}
+}
+
+func DialTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.dialTimeout = d
+	}
+}
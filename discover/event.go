@@ -0,0 +1,70 @@
+package discover
+
+// EventType 实例变化类型
+type EventType int
+
+const (
+	EventAdded EventType = iota
+	EventUpdated
+	EventRemoved
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventUpdated:
+		return "updated"
+	case EventRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event 监听到的实例变化，Removed事件中的Info为删除前的实例信息
+type Event struct {
+	Type        EventType
+	ServiceType string
+	Info        ServerInfo
+}
+
+// OnEvent 添加实例变化的回调，回调在监听协程中依次执行，不应该阻塞
+func (s *Service) OnEvent(handler func(event Event)) {
+	s.mu.Lock()
+	s.eventHandlers = append(s.eventHandlers, handler)
+	s.mu.Unlock()
+}
+
+// OnError 添加错误回调，注册和监听过程中的错误通过回调通知，监听出错以后会自动重新监听
+func (s *Service) OnError(handler func(err error)) {
+	s.mu.Lock()
+	s.errorHandlers = append(s.errorHandlers, handler)
+	s.mu.Unlock()
+}
+
+func (s *Service) emit(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	s.mu.RLock()
+	handlers := s.eventHandlers
+	s.mu.RUnlock()
+
+	for _, event := range events {
+		for _, handler := range handlers {
+			handler(event)
+		}
+	}
+}
+
+func (s *Service) reportError(err error) {
+	s.mu.RLock()
+	handlers := s.errorHandlers
+	s.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(err)
+	}
+}
@@ -0,0 +1,102 @@
+package discover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+)
+
+func waitEvent(t *testing.T, ch chan Event, typ EventType, address string) {
+	select {
+	case event := <-ch:
+		if event.Type != typ || event.ServiceType != "api" || event.Info.Address != address {
+			t.Fatalf("unexpected event: %s %+v", event.Type, event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("wait %s event timeout", typ)
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	s1, err := NewService("events", "api", "node1", "10.0.0.1:80", endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := NewService("events", "gateway", "node1", "10.0.0.2:80", endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Stop()
+
+	ch := make(chan Event, 10)
+	s2.OnEvent(func(event Event) {
+		ch <- event
+	})
+	s2.WatchNodes("/events/api")
+
+	s1.Register()
+	waitEvent(t, ch, EventAdded, "10.0.0.1:80")
+
+	// 收到事件时注册协程可能还没有保存leaseid，从etcd中获取实例绑定的租约
+	ser := s1.(*Service)
+	resp, err := ser.client.Get(context.TODO(), ser.fullPath)
+	if err != nil || len(resp.Kvs) != 1 {
+		t.Fatalf("unexpected response: %v %v", resp, err)
+	}
+
+	_, err = ser.client.Put(context.TODO(), ser.fullPath, `{"address":"10.0.0.1:80","zone":"z1"}`, clientv3.WithLease(clientv3.LeaseID(resp.Kvs[0].Lease)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, ch, EventUpdated, "10.0.0.1:80")
+
+	s1.Stop()
+	waitEvent(t, ch, EventRemoved, "10.0.0.1:80")
+}
+
+func TestWatchResync(t *testing.T) {
+	s, err := NewService("resync", "gateway", "node1", "10.0.0.2:80", endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	ser := s.(*Service)
+	resp, err := ser.client.Put(context.TODO(), "/resync/api/node1", "10.0.0.1:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ser.client.Put(context.TODO(), "/resync/api/node1", "10.0.0.3:80"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ser.client.Compact(context.TODO(), resp.Header.Revision+1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 历史版本被压缩以后监听返回错误，由watchNodes重新获取全量数据
+	if err := ser.watch("/resync/api", resp.Header.Revision-1); err == nil || err.Error() != rpctypes.ErrCompacted.Error() {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 监听中断期间下线的实例在重新获取数据时产生Removed事件
+	ch := make(chan Event, 10)
+	s.OnEvent(func(event Event) {
+		ch <- event
+	})
+	ser.addService(nil, "/resync/api/node2", "10.0.0.4:80")
+
+	if _, err := ser.getServerFirst("/resync/api"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitEvent(t, ch, EventAdded, "10.0.0.3:80")
+	waitEvent(t, ch, EventRemoved, "10.0.0.4:80")
+
+	_, _ = ser.client.Delete(context.TODO(), "/resync", clientv3.WithPrefix())
+}
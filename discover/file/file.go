@@ -0,0 +1,175 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/wpajqz/linker/discover"
+)
+
+// Registry 使用JSON文件保存服务实例，通过定时读取文件发现变化，同一个文件只应该被一个进程写入
+type Registry struct {
+	path     string
+	interval time.Duration
+	mu       sync.Mutex
+}
+
+func NewRegistry(path string, interval time.Duration) *Registry {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	return &Registry{path: path, interval: interval}
+}
+
+func (r *Registry) Register(ctx context.Context, instance discover.Instance) error {
+	if err := instance.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	for i, v := range list {
+		if v.Service == instance.Service && v.ID == instance.ID {
+			list[i] = instance
+			return r.save(list)
+		}
+	}
+
+	return r.save(append(list, instance))
+}
+
+func (r *Registry) Deregister(ctx context.Context, instance discover.Instance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	for i, v := range list {
+		if v.Service == instance.Service && v.ID == instance.ID {
+			return r.save(append(list[:i], list[i+1:]...))
+		}
+	}
+
+	return nil
+}
+
+func (r *Registry) List(ctx context.Context, service string) ([]discover.Instance, error) {
+	r.mu.Lock()
+	list, err := r.load()
+	r.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]discover.Instance, 0, len(list))
+	for _, v := range list {
+		if v.Service == service {
+			result = append(result, v)
+		}
+	}
+
+	discover.SortInstances(result)
+
+	return result, nil
+}
+
+func (r *Registry) Watch(ctx context.Context, service string) (<-chan []discover.Instance, error) {
+	last, err := r.List(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []discover.Instance, 1)
+	discover.Notify(ch, last)
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// 读取失败时等待下一次检查
+				list, err := r.List(ctx, service)
+				if err != nil || reflect.DeepEqual(list, last) {
+					continue
+				}
+
+				last = list
+				discover.Notify(ch, list)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// load 读取文件中的所有实例，文件不存在时返回空列表
+func (r *Registry) load() ([]discover.Instance, error) {
+	data, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var list []discover.Instance
+	if len(data) == 0 {
+		return list, nil
+	}
+
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// save 先写入临时文件再重命名，避免读取到写了一半的文件
+func (r *Registry) save(list []discover.Instance) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), r.path)
+}
@@ -0,0 +1,24 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker/discover"
+	"github.com/wpajqz/linker/discover/registrytest"
+)
+
+func TestRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	registrytest.Run(t, func(t *testing.T) discover.Registry {
+		return NewRegistry(filepath.Join(dir, filepath.Base(t.Name())+".json"), 10*time.Millisecond)
+	})
+}
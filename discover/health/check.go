@@ -0,0 +1,102 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/plugin"
+)
+
+// CheckFunc 检查address对应的实例是否可用，ctx结束时需要返回
+type CheckFunc func(ctx context.Context, address string) error
+
+// TCP 能够建立TCP连接即认为实例可用
+func TCP() CheckFunc {
+	return func(ctx context.Context, address string) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, linker.NetworkTCP, address)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+}
+
+// Heartbeat 向linker实例发送心跳数据包，收到成功的心跳响应才认为实例可用，
+// plugins需要和服务端的数据包插件保持一致
+func Heartbeat(plugins ...plugin.PacketPlugin) CheckFunc {
+	return func(ctx context.Context, address string) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, linker.NetworkTCP, address)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if deadline, ok := ctx.Deadline(); ok {
+			if err := conn.SetDeadline(deadline); err != nil {
+				return err
+			}
+		}
+
+		sequence := time.Now().UnixNano()
+		p, err := linker.NewPacket(linker.OperatorHeartbeat, sequence, nil, nil, plugins)
+		if err != nil {
+			return err
+		}
+
+		if _, err := conn.Write(p.Bytes()); err != nil {
+			return err
+		}
+
+		for {
+			rp, err := linker.ReadPacket(conn, plugins)
+			if err != nil {
+				return err
+			}
+
+			if rp.Operator != linker.OperatorHeartbeat || rp.Sequence != sequence {
+				continue
+			}
+
+			if message, ok := failed(rp.Header); ok {
+				return errors.New(message)
+			}
+
+			return nil
+		}
+	}
+}
+
+// failed 检查响应头中是否带有错误码
+func failed(header []byte) (string, bool) {
+	var code, message string
+	for _, v := range strings.Split(string(header), ";") {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "code":
+			code = kv[1]
+		case "message":
+			message = kv[1]
+		}
+	}
+
+	if code == "" {
+		return "", false
+	}
+
+	if message == "" {
+		message = "heartbeat failed with code " + code
+	}
+
+	return message, true
+}
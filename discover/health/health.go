@@ -0,0 +1,252 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wpajqz/linker/discover"
+)
+
+type (
+	Options struct {
+		interval  time.Duration
+		timeout   time.Duration
+		threshold int
+		check     CheckFunc
+	}
+
+	Option func(o *Options)
+
+	// State 实例的健康检查状态
+	State struct {
+		Key       string    `json:"key"`
+		Address   string    `json:"address"`
+		Healthy   bool      `json:"healthy"`
+		Failures  int       `json:"failures"`
+		LastCheck time.Time `json:"last_check"`
+		LastError string    `json:"last_error,omitempty"`
+	}
+
+	// Checker 定时检查服务发现中的实例，连续失败threshold次的实例被标记为不健康，检查成功后恢复
+	Checker struct {
+		options   Options
+		list      func(ctx context.Context) ([]discover.ServerInfo, error)
+		setHealth func(key string, status discover.HealthStatus)
+		mu        sync.RWMutex
+		states    map[string]*State
+		done      chan struct{}
+		startOnce sync.Once
+		stopOnce  sync.Once
+	}
+)
+
+// Interval 检查间隔，默认5秒
+func Interval(d time.Duration) Option {
+	return func(o *Options) {
+		o.interval = d
+	}
+}
+
+// Timeout 单次检查的超时时间，默认2秒
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.timeout = d
+	}
+}
+
+// Threshold 连续失败多少次以后标记为不健康，默认3次
+func Threshold(n int) Option {
+	return func(o *Options) {
+		o.threshold = n
+	}
+}
+
+// WithCheck 检查方式，默认发送心跳数据包
+func WithCheck(check CheckFunc) Option {
+	return func(o *Options) {
+		o.check = check
+	}
+}
+
+// NewChecker 检查服务类型为serviceType的所有实例，检查结果通过SetHealth同步到service，调用Start以后开始检查
+func NewChecker(service discover.IServicer, serviceType string, opts ...Option) *Checker {
+	c := newChecker(func(ctx context.Context) ([]discover.ServerInfo, error) {
+		return service.GetServices(serviceType), nil
+	}, opts)
+	c.setHealth = service.SetHealth
+
+	return c
+}
+
+// NewRegistryChecker 检查注册表中服务名为service的所有实例，注册表中的实例信息不会被修改，
+// 选择实例时使用Filter排除不健康的实例，调用Start以后开始检查
+func NewRegistryChecker(registry discover.Registry, service string, opts ...Option) *Checker {
+	return newChecker(func(ctx context.Context) ([]discover.ServerInfo, error) {
+		instances, err := registry.List(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+
+		list := make([]discover.ServerInfo, 0, len(instances))
+		for _, instance := range instances {
+			list = append(list, instance.Info())
+		}
+
+		return list, nil
+	}, opts)
+}
+
+func newChecker(list func(ctx context.Context) ([]discover.ServerInfo, error), opts []Option) *Checker {
+	options := Options{
+		interval:  5 * time.Second,
+		timeout:   2 * time.Second,
+		threshold: 3,
+		check:     Heartbeat(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.threshold <= 0 {
+		options.threshold = 1
+	}
+
+	return &Checker{
+		options: options,
+		list:    list,
+		states:  make(map[string]*State),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start 开始定时检查
+func (c *Checker) Start() {
+	c.startOnce.Do(func() {
+		go c.run()
+	})
+}
+
+// Stop 停止检查，已经标记的状态保持不变
+func (c *Checker) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// States 所有实例的检查状态，按照Key排序，用于调试
+func (c *Checker) States() []State {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	list := make([]State, 0, len(c.states))
+	for _, state := range c.states {
+		list = append(list, *state)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+
+	return list
+}
+
+func (c *Checker) run() {
+	ticker := time.NewTicker(c.options.interval)
+	defer ticker.Stop()
+
+	for {
+		c.checkAll()
+
+		select {
+		case <-ticker.C:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Filter 排除检查结果为不健康的实例，可以用于Select和SelectInstance
+func (c *Checker) Filter() discover.Filter {
+	return func(info discover.ServerInfo) bool {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+
+		state, ok := c.states[info.GetKey()]
+
+		return !ok || state.Healthy
+	}
+}
+
+// checkAll 并发检查当前所有实例，并清理已经下线的实例状态，获取实例失败时保留上一次的结果
+func (c *Checker) checkAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.options.timeout)
+	instances, err := c.list(ctx)
+	cancel()
+
+	if err != nil {
+		return
+	}
+
+	keys := make(map[string]bool, len(instances))
+	var wg sync.WaitGroup
+	for _, info := range instances {
+		keys[info.GetKey()] = true
+
+		wg.Add(1)
+		go func(key, address string) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), c.options.timeout)
+			err := c.options.check(ctx, address)
+			cancel()
+
+			c.report(key, address, err)
+		}(info.GetKey(), info.GetValue())
+	}
+
+	wg.Wait()
+
+	c.mu.Lock()
+	for key := range c.states {
+		if !keys[key] {
+			delete(c.states, key)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// report 记录检查结果，健康状态发生变化时同步到服务发现
+func (c *Checker) report(key, address string, err error) {
+	c.mu.Lock()
+	state, ok := c.states[key]
+	if !ok {
+		state = &State{Key: key, Healthy: true}
+		c.states[key] = state
+	}
+
+	state.Address = address
+	state.LastCheck = time.Now()
+
+	var status discover.HealthStatus
+	if err != nil {
+		state.Failures++
+		state.LastError = err.Error()
+		if state.Healthy && state.Failures >= c.options.threshold {
+			state.Healthy = false
+			status = discover.HealthCritical
+		}
+	} else {
+		state.Failures = 0
+		state.LastError = ""
+		if !state.Healthy {
+			state.Healthy = true
+			status = discover.HealthPassing
+		}
+	}
+	c.mu.Unlock()
+
+	if status != "" && c.setHealth != nil {
+		c.setHealth(key, status)
+	}
+}
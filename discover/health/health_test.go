@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/discover"
+	"github.com/wpajqz/linker/discover/internal/etcdtest"
+	"github.com/wpajqz/linker/discover/memory"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+var endpoints []string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "health")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	e, address, err := etcdtest.Start(dir)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	endpoints = []string{address}
+	code := m.Run()
+
+	e.Close()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func startServer(t *testing.T, address string) *linker.Server {
+	s := linker.NewServer(linker.WithTCPEndpoint(linker.Endpoint{Address: address}))
+	servertest.Run(t, s, address)
+
+	return s
+}
+
+func waitHealthy(t *testing.T, c *Checker, healthy bool) {
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if states := c.States(); len(states) == 1 && states[0].Healthy == healthy {
+			return
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatalf("unexpected states: %+v", c.States())
+}
+
+func TestChecker(t *testing.T) {
+	address := servertest.Address(t)
+	s := startServer(t, address)
+
+	node, err := discover.NewService("health", "api", "node1", address, endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Stop()
+	node.Register()
+
+	service, err := discover.NewService("health", "gateway", "node1", "127.0.0.1:0", endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Stop()
+	service.WatchNodes("/health/api")
+
+	c := NewChecker(service, "api", Interval(50*time.Millisecond), Timeout(100*time.Millisecond), Threshold(2))
+	c.Start()
+	defer c.Stop()
+
+	waitHealthy(t, c, true)
+	if info, err := service.Select("api"); err != nil || info.GetValue() != address {
+		t.Fatalf("unexpected result: %+v %v", info, err)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 连续检查失败以后实例不会被选中
+	waitHealthy(t, c, false)
+	if _, err := service.Select("api"); err != discover.ErrorNoMatchingInstance {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if states := c.States(); states[0].Failures < 2 || states[0].LastError == "" {
+		t.Fatalf("unexpected states: %+v", states)
+	}
+
+	// 服务恢复以后重新可以被选中
+	startServer(t, address)
+
+	waitHealthy(t, c, true)
+	if _, err := service.Select("api"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistryChecker(t *testing.T) {
+	address := servertest.Address(t)
+	s := startServer(t, address)
+
+	registry := memory.NewRegistry()
+	if err := registry.Register(context.Background(), discover.Instance{ID: "node1", Service: "api", Address: address}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewRegistryChecker(registry, "api", Interval(50*time.Millisecond), Timeout(100*time.Millisecond), Threshold(2))
+	c.Start()
+	defer c.Stop()
+
+	selectInstance := func() (discover.Instance, error) {
+		list, err := registry.List(context.Background(), "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return discover.SelectInstance(list, c.Filter())
+	}
+
+	waitHealthy(t, c, true)
+	if instance, err := selectInstance(); err != nil || instance.Address != address {
+		t.Fatalf("unexpected result: %+v %v", instance, err)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 注册表中的实例不变，通过Filter排除不健康的实例
+	waitHealthy(t, c, false)
+	if _, err := selectInstance(); err != discover.ErrorNoMatchingInstance {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	startServer(t, address)
+
+	waitHealthy(t, c, true)
+	if _, err := selectInstance(); err != nil {
+		t.Fatal(err)
+	}
+}
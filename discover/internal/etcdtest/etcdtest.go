@@ -0,0 +1,55 @@
+package etcdtest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"go.etcd.io/etcd/embed"
+)
+
+// Start 启动内嵌的etcd服务用于测试，返回服务以及客户端访问地址
+func Start(dir string) (*embed.Etcd, string, error) {
+	clientURL, err := freeURL()
+	if err != nil {
+		return nil, "", err
+	}
+
+	peerURL, err := freeURL()
+	if err != nil {
+		return nil, "", err
+	}
+
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	cfg.LCUrls, cfg.ACUrls = []url.URL{*clientURL}, []url.URL{*clientURL}
+	cfg.LPUrls, cfg.APUrls = []url.URL{*peerURL}, []url.URL{*peerURL}
+	cfg.InitialCluster = cfg.Name + "=" + peerURL.String()
+	cfg.LogPkgLevels = "*=C"
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(30 * time.Second):
+		e.Close()
+		return nil, "", errors.New("etcdtest: server start timeout")
+	}
+
+	return e, clientURL.Host, nil
+}
+
+func freeURL() (*url.URL, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	return url.Parse(fmt.Sprintf("http://%s", l.Addr().String()))
+}
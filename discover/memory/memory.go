@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/wpajqz/linker/discover"
+)
+
+// Registry 进程内的服务注册表，适用于测试以及单机部署
+type Registry struct {
+	mu       sync.Mutex
+	services map[string]map[string]discover.Instance
+	watchers map[string]map[chan []discover.Instance]struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		services: make(map[string]map[string]discover.Instance),
+		watchers: make(map[string]map[chan []discover.Instance]struct{}),
+	}
+}
+
+func (r *Registry) Register(ctx context.Context, instance discover.Instance) error {
+	if err := instance.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.services[instance.Service] == nil {
+		r.services[instance.Service] = make(map[string]discover.Instance)
+	}
+
+	r.services[instance.Service][instance.ID] = instance
+	r.notify(instance.Service)
+
+	return nil
+}
+
+func (r *Registry) Deregister(ctx context.Context, instance discover.Instance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.services[instance.Service][instance.ID]; !ok {
+		return nil
+	}
+
+	delete(r.services[instance.Service], instance.ID)
+	r.notify(instance.Service)
+
+	return nil
+}
+
+func (r *Registry) List(ctx context.Context, service string) ([]discover.Instance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.list(service), nil
+}
+
+func (r *Registry) Watch(ctx context.Context, service string) (<-chan []discover.Instance, error) {
+	ch := make(chan []discover.Instance, 1)
+
+	r.mu.Lock()
+	if r.watchers[service] == nil {
+		r.watchers[service] = make(map[chan []discover.Instance]struct{})
+	}
+	r.watchers[service][ch] = struct{}{}
+	discover.Notify(ch, r.list(service))
+	r.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		r.mu.Lock()
+		delete(r.watchers[service], ch)
+		close(ch)
+		r.mu.Unlock()
+	}()
+
+	return ch, nil
+}
+
+func (r *Registry) list(service string) []discover.Instance {
+	list := make([]discover.Instance, 0, len(r.services[service]))
+	for _, instance := range r.services[service] {
+		list = append(list, instance)
+	}
+
+	discover.SortInstances(list)
+
+	return list
+}
+
+func (r *Registry) notify(service string) {
+	list := r.list(service)
+	for ch := range r.watchers[service] {
+		discover.Notify(ch, list)
+	}
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/wpajqz/linker/discover"
+	"github.com/wpajqz/linker/discover/registrytest"
+)
+
+func TestRegistry(t *testing.T) {
+	registrytest.Run(t, func(t *testing.T) discover.Registry {
+		return NewRegistry()
+	})
+}
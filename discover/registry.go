@@ -0,0 +1,116 @@
+package discover
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 实例Metadata中用于筛选和选择实例的key，与ServerInfo中同名的字段含义相同
+const (
+	MetadataWeight = "weight"
+	MetadataZone   = "zone"
+	MetadataTags   = "tags" // 多个标签使用逗号分隔
+	MetadataHealth = "health"
+)
+
+type (
+	// Instance 注册到服务发现中的服务实例
+	Instance struct {
+		ID       string            `json:"id"`
+		Service  string            `json:"service"`
+		Address  string            `json:"address"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+	}
+
+	// Registry 服务注册与发现
+	Registry interface {
+		// Register 注册实例，ID相同的实例会被覆盖
+		Register(ctx context.Context, instance Instance) error
+		// Deregister 注销实例，实例不存在时不返回错误
+		Deregister(ctx context.Context, instance Instance) error
+		// List 获取服务的所有实例，按照ID排序
+		List(ctx context.Context, service string) ([]Instance, error)
+		// Watch 监听服务的实例变化，每次变化推送完整的实例列表，只保留最新的结果，ctx结束以后channel被关闭
+		Watch(ctx context.Context, service string) (<-chan []Instance, error)
+	}
+)
+
+// Validate 检查实例信息是否完整
+func (i Instance) Validate() error {
+	if i.Service == "" {
+		return ErrorServiceNameNil
+	}
+
+	if i.ID == "" {
+		return ErrorInstanceIDNil
+	}
+
+	if i.Address == "" {
+		return ErrorServiceIPInfoNil
+	}
+
+	return nil
+}
+
+// Info 把Metadata中的权重、可用区、标签和健康状态转换为ServerInfo，Filter和选择实例都基于该结果
+func (i Instance) Info() ServerInfo {
+	info := ServerInfo{
+		key:      i.ID,
+		Address:  i.Address,
+		Zone:     i.Metadata[MetadataZone],
+		Health:   HealthStatus(i.Metadata[MetadataHealth]),
+		Metadata: i.Metadata,
+	}
+
+	info.Weight, _ = strconv.Atoi(i.Metadata[MetadataWeight])
+
+	for _, tag := range strings.Split(i.Metadata[MetadataTags], ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			info.Tags = append(info.Tags, tag)
+		}
+	}
+
+	return info
+}
+
+// SelectInstance 从健康的实例中筛选出符合条件的实例，并按照权重随机选择一个
+func SelectInstance(list []Instance, filters ...Filter) (Instance, error) {
+	if len(list) == 0 {
+		return Instance{}, ErrorServiceNotFound
+	}
+
+	var (
+		matched []Instance
+		infos   []ServerInfo
+	)
+
+	for _, instance := range list {
+		if info := instance.Info(); match(info, filters) {
+			matched = append(matched, instance)
+			infos = append(infos, info)
+		}
+	}
+
+	if len(matched) == 0 {
+		return Instance{}, ErrorNoMatchingInstance
+	}
+
+	return matched[weighted(infos)], nil
+}
+
+// SortInstances 按照ID排序
+func SortInstances(list []Instance) {
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+}
+
+// Notify 向监听者推送最新的实例列表，监听者还没有读取的旧结果会被丢弃，同一个channel只能有一个发送方
+func Notify(ch chan []Instance, list []Instance) {
+	select {
+	case <-ch:
+	default:
+	}
+
+	ch <- list
+}
@@ -0,0 +1,200 @@
+// Package registrytest 提供discover.Registry实现需要通过的一致性测试
+package registrytest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker/discover"
+)
+
+// Timeout 等待Watch推送结果的最长时间
+var Timeout = 5 * time.Second
+
+// Run 运行一致性测试，newRegistry每次需要返回一个空的注册表
+func Run(t *testing.T, newRegistry func(t *testing.T) discover.Registry) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, r discover.Registry)
+	}{
+		{"RegisterList", testRegisterList},
+		{"Update", testUpdate},
+		{"Deregister", testDeregister},
+		{"Isolation", testIsolation},
+		{"Validate", testValidate},
+		{"Watch", testWatch},
+		{"WatchCancel", testWatchCancel},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRegistry(t))
+		})
+	}
+}
+
+func instance(id, address string) discover.Instance {
+	return discover.Instance{ID: id, Service: "echo", Address: address, Metadata: map[string]string{"version": "v1"}}
+}
+
+func register(t *testing.T, r discover.Registry, instances ...discover.Instance) {
+	t.Helper()
+
+	for _, instance := range instances {
+		if err := r.Register(context.Background(), instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func expect(t *testing.T, r discover.Registry, service string, want ...discover.Instance) {
+	t.Helper()
+
+	list, err := r.List(context.Background(), service)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) == 0 && len(want) == 0 {
+		return
+	}
+
+	if !reflect.DeepEqual(list, want) {
+		t.Fatalf("List(%q) = %v, want %v", service, list, want)
+	}
+}
+
+// wait 等待Watch推送的结果满足条件
+func wait(t *testing.T, ch <-chan []discover.Instance, want ...discover.Instance) {
+	t.Helper()
+
+	timeout := time.After(Timeout)
+	for {
+		select {
+		case list, ok := <-ch:
+			if !ok {
+				t.Fatal("watch channel closed")
+			}
+
+			if (len(list) == 0 && len(want) == 0) || reflect.DeepEqual(list, want) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("watch timeout, want %v", want)
+		}
+	}
+}
+
+func testRegisterList(t *testing.T, r discover.Registry) {
+	a, b := instance("b", "127.0.0.1:2"), instance("a", "127.0.0.1:1")
+	register(t, r, a, b)
+
+	expect(t, r, "echo", b, a)
+}
+
+func testUpdate(t *testing.T, r discover.Registry) {
+	a := instance("a", "127.0.0.1:1")
+	register(t, r, a)
+
+	a.Address = "127.0.0.1:3"
+	a.Metadata = map[string]string{"version": "v2"}
+	register(t, r, a)
+
+	expect(t, r, "echo", a)
+}
+
+func testDeregister(t *testing.T, r discover.Registry) {
+	a, b := instance("a", "127.0.0.1:1"), instance("b", "127.0.0.1:2")
+	register(t, r, a, b)
+
+	if err := r.Deregister(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, r, "echo", b)
+
+	// 注销不存在的实例不返回错误
+	if err := r.Deregister(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testIsolation(t *testing.T, r discover.Registry) {
+	a := instance("a", "127.0.0.1:1")
+	other := discover.Instance{ID: "a", Service: "other", Address: "127.0.0.1:9"}
+	register(t, r, a, other)
+
+	expect(t, r, "echo", a)
+	expect(t, r, "other", other)
+	expect(t, r, "missing")
+}
+
+func testValidate(t *testing.T, r discover.Registry) {
+	for _, instance := range []discover.Instance{
+		{Service: "echo", Address: "127.0.0.1:1"},
+		{ID: "a", Address: "127.0.0.1:1"},
+		{ID: "a", Service: "echo"},
+	} {
+		if err := r.Register(context.Background(), instance); err == nil {
+			t.Fatalf("Register(%v) should fail", instance)
+		}
+	}
+}
+
+func testWatch(t *testing.T, r discover.Registry) {
+	a, b := instance("a", "127.0.0.1:1"), instance("b", "127.0.0.1:2")
+	register(t, r, a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := r.Watch(ctx, "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次推送当前的实例列表
+	wait(t, ch, a)
+
+	register(t, r, b)
+	wait(t, ch, a, b)
+
+	a.Address = "127.0.0.1:3"
+	register(t, r, a)
+	wait(t, ch, a, b)
+
+	if err := r.Deregister(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, ch, b)
+
+	if err := r.Deregister(context.Background(), b); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, ch)
+}
+
+func testWatchCancel(t *testing.T, r discover.Registry) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch, err := r.Watch(ctx, "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	timeout := time.After(Timeout)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("watch channel not closed after cancel")
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package discover
+
+import "math/rand"
+
+// Filter 筛选服务实例，返回false的实例不会被选中
+type Filter func(info ServerInfo) bool
+
+// Tagged 只选择带有全部指定标签的实例
+func Tagged(tags ...string) Filter {
+	return func(info ServerInfo) bool {
+		for _, tag := range tags {
+			if !info.HasTag(tag) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// InZone 只选择指定可用区的实例
+func InZone(zone string) Filter {
+	return func(info ServerInfo) bool {
+		return info.Zone == zone
+	}
+}
+
+// Select 从服务类型为t的健康实例中筛选出符合条件的实例，并按照权重随机选择一个
+func (s *Service) Select(t string, filters ...Filter) (ServerInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.services[t]) == 0 {
+		return ServerInfo{}, ErrorServiceNotFound
+	}
+
+	var list []ServerInfo
+	for _, info := range s.services[t] {
+		info = s.view(info)
+		if match(info, filters) {
+			list = append(list, info)
+		}
+	}
+
+	if len(list) == 0 {
+		return ServerInfo{}, ErrorNoMatchingInstance
+	}
+
+	return list[weighted(list)], nil
+}
+
+// weighted 按照权重随机选择一个实例，返回实例的下标，list不能为空
+func weighted(list []ServerInfo) int {
+	var total int
+	for _, info := range list {
+		total += info.weight()
+	}
+
+	n := rand.Intn(total)
+	for i, info := range list {
+		if n -= info.weight(); n < 0 {
+			return i
+		}
+	}
+
+	return len(list) - 1
+}
+
+func match(info ServerInfo, filters []Filter) bool {
+	if !info.Healthy() {
+		return false
+	}
+
+	for _, filter := range filters {
+		if !filter(info) {
+			return false
+		}
+	}
+
+	return true
+}
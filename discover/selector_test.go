@@ -0,0 +1,117 @@
+package discover
+
+import "testing"
+
+func newTestService(infos ...ServerInfo) *Service {
+	s := &Service{services: map[string]map[string]ServerInfo{}}
+	for _, info := range infos {
+		if s.services["api"] == nil {
+			s.services["api"] = map[string]ServerInfo{}
+		}
+
+		s.services["api"][info.Address] = info
+	}
+
+	return s
+}
+
+func TestParseServerInfo(t *testing.T) {
+	info := parseServerInfo("/test/api/a", `{"address":"10.0.0.1:80","tags":["grpc"],"weight":3,"zone":"z1","health":"passing"}`)
+	if info.GetKey() != "/test/api/a" || info.GetValue() != "10.0.0.1:80" || !info.HasTag("grpc") || info.Weight != 3 || info.Zone != "z1" || !info.Healthy() {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+
+	// 兼容直接保存地址的旧格式
+	info = parseServerInfo("/test/api/b", "10.0.0.2:80")
+	if info.GetValue() != "10.0.0.2:80" || !info.Healthy() {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	s := newTestService(
+		ServerInfo{Address: "a", Tags: []string{"v2"}, Zone: "z1", Weight: 1},
+		ServerInfo{Address: "b", Tags: []string{"v2", "canary"}, Zone: "z2", Weight: 3},
+		ServerInfo{Address: "c", Tags: []string{"v2"}, Zone: "z1", Health: HealthCritical},
+	)
+
+	if _, err := s.Select("none"); err != ErrorServiceNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.GetServiceType("none") != "" {
+		t.Fatal("expected empty address for unknown service type")
+	}
+
+	if _, err := s.Select("api", Tagged("v3")); err != ErrorNoMatchingInstance {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		info, err := s.Select("api", InZone("z1"))
+		if err != nil || info.Address != "a" {
+			t.Fatalf("unexpected result: %+v %v", info, err)
+		}
+
+		info, err = s.Select("api", Tagged("v2", "canary"))
+		if err != nil || info.Address != "b" {
+			t.Fatalf("unexpected result: %+v %v", info, err)
+		}
+	}
+
+	// 按照权重随机选择，不健康的实例不会被选中
+	count := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		info, err := s.Select("api")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count[info.Address]++
+	}
+
+	if count["c"] != 0 || count["b"] < 2*count["a"] {
+		t.Fatalf("unexpected distribution: %v", count)
+	}
+}
+
+func TestSelectInstance(t *testing.T) {
+	list := []Instance{
+		{ID: "a", Service: "api", Address: "a", Metadata: map[string]string{MetadataZone: "z1", MetadataTags: "v2"}},
+		{ID: "b", Service: "api", Address: "b", Metadata: map[string]string{MetadataZone: "z2", MetadataTags: "v2, canary", MetadataWeight: "3"}},
+		{ID: "c", Service: "api", Address: "c", Metadata: map[string]string{MetadataZone: "z1", MetadataHealth: string(HealthCritical)}},
+	}
+
+	info := list[1].Info()
+	if info.GetKey() != "b" || info.Weight != 3 || info.Zone != "z2" || !info.HasTag("canary") || !info.Healthy() {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+
+	if _, err := SelectInstance(nil); err != ErrorServiceNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := SelectInstance(list, Tagged("v3")); err != ErrorNoMatchingInstance {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		instance, err := SelectInstance(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts[instance.ID]++
+
+		// 不健康的实例不会被选中
+		if instance, err := SelectInstance(list, InZone("z1")); err != nil || instance.ID != "a" {
+			t.Fatalf("unexpected result: %+v %v", instance, err)
+		}
+	}
+
+	// 按照权重随机选择
+	if counts["c"] != 0 || counts["b"] < 2*counts["a"] {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+}
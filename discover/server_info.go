@@ -1,13 +1,69 @@
 package discover
 
-// ServerInfo server info with etcd full path and val (ip info)
+import (
+	"encoding/json"
+	"strings"
+)
+
+// HealthStatus 服务实例的健康状态
+type HealthStatus string
+
+const (
+	HealthPassing  HealthStatus = "passing"
+	HealthCritical HealthStatus = "critical"
+)
+
+// ServerInfo server info with etcd full path and instance record, the record is stored as JSON.
+// Registry中的Instance通过Info转换为ServerInfo，两者使用相同的筛选和选择逻辑。
+// 旧版本的读取方会把JSON整体当作地址使用，新旧版本混合部署时需要先升级所有读取方，再升级注册方
 type ServerInfo struct {
-	key string
-	val string
+	key      string
+	Address  string            `json:"address"`
+	Tags     []string          `json:"tags,omitempty"`
+	Weight   int               `json:"weight,omitempty"`
+	Zone     string            `json:"zone,omitempty"`
+	Health   HealthStatus      `json:"health,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // GetKey GetKey
 func (s *ServerInfo) GetKey() string { return s.key }
 
-// GetValue GetValue
-func (s *ServerInfo) GetValue() string { return s.val }
+// GetValue 实例地址 (ip info)
+func (s *ServerInfo) GetValue() string { return s.Address }
+
+// HasTag 实例是否带有指定的标签
+func (s *ServerInfo) HasTag(tag string) bool {
+	for _, v := range s.Tags {
+		if v == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Healthy 没有上报健康状态的实例视为健康
+func (s *ServerInfo) Healthy() bool {
+	return s.Health != HealthCritical
+}
+
+// weight 没有设置权重的实例权重为1
+func (s *ServerInfo) weight() int {
+	if s.Weight <= 0 {
+		return 1
+	}
+
+	return s.Weight
+}
+
+// parseServerInfo 解析etcd中保存的实例信息，兼容旧版本直接保存地址的格式
+func parseServerInfo(key, value string) ServerInfo {
+	info := ServerInfo{}
+	if strings.HasPrefix(strings.TrimSpace(value), "{") && json.Unmarshal([]byte(value), &info) == nil {
+		info.key = key
+		return info
+	}
+
+	return ServerInfo{key: key, Address: value}
+}
@@ -2,9 +2,10 @@ package discover
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"math/rand"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -18,11 +19,22 @@ type IServicer interface {
 	Register()
 	WatchNodes(string)
 	GetServiceType(string) string
+	GetServices(string) []ServerInfo
+	Select(string, ...Filter) (ServerInfo, error)
+	OnEvent(func(Event))
+	OnError(func(error))
+	SetHealth(string, HealthStatus)
 }
 
+// watchRetryInterval 监听出错以后重新监听的间隔
+const watchRetryInterval = time.Second
+
+// ServiceOption 设置注册到etcd中的实例信息
+type ServiceOption func(info *ServerInfo)
+
 // Service a service
 type Service struct {
-	ipInfo      string //etcd val
+	ipInfo      string //etcd val, JSON encoded ServerInfo
 	stop        chan bool
 	leaseid     clientv3.LeaseID
 	client      *clientv3.Client
@@ -35,10 +47,47 @@ type Service struct {
 	// map[type]map[fullPath]ServerInfo
 	services map[string]map[string]ServerInfo
 	mu       sync.RWMutex
+
+	// 健康检查失败的实例，map[fullPath]struct{}
+	unhealthy map[string]struct{}
+
+	eventHandlers []func(event Event)
+	errorHandlers []func(err error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// WithTags 实例标签，用于筛选实例
+func WithTags(tags ...string) ServiceOption {
+	return func(info *ServerInfo) {
+		info.Tags = append(info.Tags, tags...)
+	}
+}
+
+// WithWeight 实例权重，选择实例时按照权重随机
+func WithWeight(weight int) ServiceOption {
+	return func(info *ServerInfo) {
+		info.Weight = weight
+	}
+}
+
+// WithZone 实例所在的可用区
+func WithZone(zone string) ServiceOption {
+	return func(info *ServerInfo) {
+		info.Zone = zone
+	}
+}
+
+// WithMetadata 实例的其他自定义信息
+func WithMetadata(metadata map[string]string) ServiceOption {
+	return func(info *ServerInfo) {
+		info.Metadata = metadata
+	}
 }
 
 // NewService init with a parameter check,t is a srever type
-func NewService(path, t, name, ip string, endpoints []string) (IServicer, error) {
+func NewService(path, t, name, ip string, endpoints []string, opts ...ServiceOption) (IServicer, error) {
 	if path == "" {
 		return nil, ErrorServicePathNil
 	}
@@ -51,6 +100,16 @@ func NewService(path, t, name, ip string, endpoints []string) (IServicer, error)
 	if ip == "" {
 		return nil, ErrorServiceIPInfoNil
 	}
+	info := ServerInfo{Address: ip, Health: HealthPassing}
+	for _, o := range opts {
+		o(&info)
+	}
+
+	value, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
 	path = formatPath(path)
 	t = formatPath(t)
 	name = formatPath(name)
@@ -62,22 +121,28 @@ func NewService(path, t, name, ip string, endpoints []string) (IServicer, error)
 	if err != nil {
 		return nil, err
 	}
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Service{
+		ctx:         ctx,
+		cancel:      cancel,
 		client:      etcdcli,
 		stop:        make(chan bool, 1),
-		ipInfo:      ip,
+		ipInfo:      string(value),
 		servicePath: path,
 		name:        name,
 		serviceType: t,
 		fullPath:    path + t + name,
 		services:    map[string]map[string]ServerInfo{},
+		unhealthy:   map[string]struct{}{},
 	}, nil
 
 }
 
 func (s *Service) Register() {
 	go func() {
-		_ = s.register()
+		if err := s.register(); err != nil {
+			s.reportError(err)
+		}
 	}()
 }
 
@@ -102,9 +167,14 @@ func (s *Service) register() error {
 	}
 }
 
-// Stop 停止
+// Stop 停止注册和监听
 func (s *Service) Stop() {
-	s.stop <- true
+	s.cancel()
+
+	select {
+	case s.stop <- true:
+	default:
+	}
 }
 
 // keepAlive 保持连接
@@ -131,112 +201,214 @@ func (s *Service) revoke() error {
 }
 
 func (s *Service) WatchNodes(path string) {
-	go func() {
-		_ = s.watchNodes(path)
-	}()
+	go s.watchNodes(path)
 }
 
-// WatchNodes WatchNodes
-func (s *Service) watchNodes(path string) error {
-	if err := s.getServerFirst(path); err != nil {
-		return err
+// watchNodes 监听path下的实例变化，监听出错、历史版本被压缩或者连接断开时重新获取全量数据后继续监听
+func (s *Service) watchNodes(path string) {
+	for {
+		revision, err := s.getServerFirst(path)
+		if err == nil {
+			err = s.watch(path, revision)
+		}
+
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			s.reportError(err)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
 	}
-	rch := s.client.Watch(context.TODO(), path, clientv3.WithPrefix())
+}
 
+// watch 从revision之后开始监听，返回时需要重新获取全量数据
+func (s *Service) watch(path string, revision int64) error {
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(s.ctx))
+	defer cancel()
+
+	rch := s.client.Watch(ctx, path, clientv3.WithPrefix(), clientv3.WithRev(revision+1))
 	for wresp := range rch {
+		if err := wresp.Err(); err != nil {
+			return err
+		}
+
+		var events []Event
 		for _, ev := range wresp.Events {
-			if string(ev.Kv.Key) == s.fullPath {
-				continue
-			}
 			switch ev.Type {
 			case clientv3.EventTypePut:
-				s.addService(string(ev.Kv.Key), string(ev.Kv.Value))
+				events = s.addService(events, string(ev.Kv.Key), string(ev.Kv.Value))
 			case clientv3.EventTypeDelete:
-				s.removeService(string(ev.Kv.Key))
+				events = s.removeService(events, string(ev.Kv.Key))
 			}
 		}
+
+		s.emit(events)
 	}
-	return nil
+
+	return ErrorWatchClosed
 }
 
 // add a service
-func (s *Service) addService(key, value string) {
+func (s *Service) addService(events []Event, key, value string) []Event {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.fullPath == key {
-		return
+		return events
 	}
 
 	// name check
-	serviceName := strings.Replace(key, "\\", "/", -1)
-	serviceType := filepath.Base(filepath.Dir(serviceName))
+	serviceType := typeOf(key)
 
 	// try new service kind init
 	if s.services[serviceType] == nil {
 		s.services[serviceType] = map[string]ServerInfo{}
 	}
 
+	// key已经存在时更新value
 	service := s.services[serviceType]
-	if _, ok := service[key]; ok {
-		return
-	}
-	service[key] = ServerInfo{
-		key: key,
-		val: value,
+	info := parseServerInfo(key, value)
+	old, ok := service[key]
+	service[key] = info
+
+	switch {
+	case !ok:
+		return append(events, Event{Type: EventAdded, ServiceType: serviceType, Info: s.view(info)})
+	case !reflect.DeepEqual(old, info):
+		return append(events, Event{Type: EventUpdated, ServiceType: serviceType, Info: s.view(info)})
+	default:
+		return events
 	}
 }
 
 // remove a service
-func (s *Service) removeService(key string) {
+func (s *Service) removeService(events []Event, key string) []Event {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	// name check
-	serviceName := strings.Replace(key, "\\", "/", -1)
-	serviceType := filepath.Base(filepath.Dir(serviceName))
+	serviceType := typeOf(key)
 	// check service kind
 	service := s.services[serviceType]
 	if service == nil {
-		return
+		return events
 	}
 
 	// remove a service
-	_, ok := service[key]
+	info, ok := service[key]
 	if !ok {
-		return
+		return events
 	}
-	delete(service, serviceName)
+	info = s.view(info)
+	delete(service, key)
+	delete(s.unhealthy, key)
+
+	return append(events, Event{Type: EventRemoved, ServiceType: serviceType, Info: info})
 }
 
 // GetServiceType get ip by server type return empty if don't have it
 func (s *Service) GetServiceType(t string) string {
+	info, err := s.Select(t)
+	if err != nil {
+		return ""
+	}
+
+	return info.GetValue()
+}
+
+// GetServices get all watched services of the server type
+func (s *Service) GetServices(t string) []ServerInfo {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	m, ok := s.services[t]
+
+	list := make([]ServerInfo, 0, len(s.services[t]))
+	for _, item := range s.services[t] {
+		list = append(list, s.view(item))
+	}
+
+	return list
+}
+
+// SetHealth 设置实例健康检查的结果，检查失败的实例在恢复之前视为不健康，状态变化时产生Updated事件
+func (s *Service) SetHealth(key string, status HealthStatus) {
+	s.mu.Lock()
+	info, ok := s.services[typeOf(key)][key]
 	if !ok {
-		return ""
+		s.mu.Unlock()
+		return
 	}
-	list := []string{}
-	for _, item := range m {
-		list = append(list, item.GetValue())
+
+	_, unhealthy := s.unhealthy[key]
+	if unhealthy == (status == HealthCritical) {
+		s.mu.Unlock()
+		return
+	}
+
+	if status == HealthCritical {
+		s.unhealthy[key] = struct{}{}
+	} else {
+		delete(s.unhealthy, key)
 	}
-	return list[rand.Intn(len(list))]
+
+	event := Event{Type: EventUpdated, ServiceType: typeOf(key), Info: s.view(info)}
+	s.mu.Unlock()
+
+	s.emit([]Event{event})
 }
 
-// getServerOnce get watch path at first time
-func (s *Service) getServerFirst(path string) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+// view 合并健康检查的结果，调用方需要持有锁
+func (s *Service) view(info ServerInfo) ServerInfo {
+	if _, ok := s.unhealthy[info.key]; ok {
+		info.Health = HealthCritical
+	}
+
+	return info
+}
+
+// getServerFirst 获取path下的全量数据，删除监听中断期间已经下线的实例，返回数据对应的版本
+func (s *Service) getServerFirst(path string) (int64, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
 	resp, err := s.client.Get(ctx, path, clientv3.WithPrefix())
 	cancel()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	if resp != nil && resp.Kvs != nil {
-		for _, item := range resp.Kvs {
-			s.addService(string(item.Key), string(item.Value))
+	var events []Event
+	keys := make(map[string]bool, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		keys[string(item.Key)] = true
+		events = s.addService(events, string(item.Key), string(item.Value))
+	}
+
+	s.mu.RLock()
+	var removed []string
+	for _, service := range s.services {
+		for key := range service {
+			if strings.HasPrefix(key, path) && !keys[key] {
+				removed = append(removed, key)
+			}
 		}
 	}
-	return nil
+	s.mu.RUnlock()
+
+	for _, key := range removed {
+		events = s.removeService(events, key)
+	}
+
+	s.emit(events)
+
+	return resp.Header.Revision, nil
+}
+
+// typeOf 实例所属的服务类型，为key的上一级目录
+func typeOf(key string) string {
+	return filepath.Base(filepath.Dir(strings.Replace(key, "\\", "/", -1)))
 }
 
 func formatPath(path string) string {
@@ -3,10 +3,38 @@ package discover
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
+
+	"github.com/wpajqz/linker/discover/internal/etcdtest"
 )
 
+// endpoints 内嵌etcd服务的地址，测试不依赖外部服务
+var endpoints []string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "discover")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	e, address, err := etcdtest.Start(dir)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	endpoints = []string{address}
+	code := m.Run()
+
+	e.Close()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestFormatPath(t *testing.T) {
 	s1 := formatPath("/")
 	if s1 != "/" {
@@ -23,7 +51,7 @@ func TestFormatPath(t *testing.T) {
 }
 
 func TestRegister(t *testing.T) {
-	s, err := NewService("test", "test_type", "test1", "test_ip", []string{"127.0.0.1:2379"})
+	s, err := NewService("test", "test_type", "test1", "test_ip", endpoints)
 	if err != nil {
 		fmt.Println("new service err:", err)
 	}
@@ -46,17 +74,17 @@ func TestRegister(t *testing.T) {
 	if string(kv.Key) != ser.fullPath {
 		t.Fail()
 	}
-	if string(kv.Value) != "test_ip" {
+	if info := parseServerInfo(string(kv.Key), string(kv.Value)); info.Address != "test_ip" || !info.Healthy() {
 		t.Fail()
 	}
 }
 
 func TestWatchAfter(t *testing.T) {
-	s1, err := NewService("test", "test_type", "test1", "test_ip1", []string{"127.0.0.1:2379"})
+	s1, err := NewService("test", "test_type", "test1", "test_ip1", endpoints)
 	if err != nil {
 		fmt.Println("new service err:", err)
 	}
-	s2, err := NewService("test", "test_type", "test2", "test_ip2", []string{"127.0.0.1:2379"})
+	s2, err := NewService("test", "test_type", "test2", "test_ip2", endpoints)
 	if err != nil {
 		fmt.Println("new service err:", err)
 	}
@@ -73,12 +101,12 @@ func TestWatchAfter(t *testing.T) {
 }
 
 func TestWatchBefore(t *testing.T) {
-	s1, err := NewService("test", "test_type", "test1", "test_ip1", []string{"127.0.0.1:2379"})
+	s1, err := NewService("test", "test_type", "test1", "test_ip1", endpoints)
 	if err != nil {
 		fmt.Println("new service err:", err)
 	}
 	s1.Register()
-	s2, err := NewService("test", "test_type", "test2", "test_ip2", []string{"127.0.0.1:2379"})
+	s2, err := NewService("test", "test_type", "test2", "test_ip2", endpoints)
 	if err != nil {
 		fmt.Println("new service err:", err)
 	}
@@ -0,0 +1,49 @@
+package static
+
+import (
+	"context"
+
+	"github.com/wpajqz/linker/discover"
+)
+
+// Registry 固定的服务实例列表，只读，不支持注册和注销
+type Registry struct {
+	services map[string][]discover.Instance
+}
+
+func NewRegistry(instances ...discover.Instance) *Registry {
+	r := &Registry{services: make(map[string][]discover.Instance)}
+	for _, instance := range instances {
+		r.services[instance.Service] = append(r.services[instance.Service], instance)
+	}
+
+	for _, list := range r.services {
+		discover.SortInstances(list)
+	}
+
+	return r
+}
+
+func (r *Registry) Register(ctx context.Context, instance discover.Instance) error {
+	return discover.ErrorReadOnly
+}
+
+func (r *Registry) Deregister(ctx context.Context, instance discover.Instance) error {
+	return discover.ErrorReadOnly
+}
+
+func (r *Registry) List(ctx context.Context, service string) ([]discover.Instance, error) {
+	return append([]discover.Instance{}, r.services[service]...), nil
+}
+
+func (r *Registry) Watch(ctx context.Context, service string) (<-chan []discover.Instance, error) {
+	ch := make(chan []discover.Instance, 1)
+	discover.Notify(ch, append([]discover.Instance{}, r.services[service]...))
+
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,38 @@
+package static
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wpajqz/linker/discover"
+)
+
+func TestRegistry(t *testing.T) {
+	a := discover.Instance{ID: "a", Service: "echo", Address: "127.0.0.1:1"}
+	b := discover.Instance{ID: "b", Service: "echo", Address: "127.0.0.1:2"}
+	r := NewRegistry(b, a)
+
+	list, err := r.List(context.Background(), "echo")
+	if err != nil || len(list) != 2 || list[0].ID != "a" || list[1].ID != "b" {
+		t.Fatalf("unexpected list: %v %v", list, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := r.Watch(ctx, "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list := <-ch; len(list) != 2 {
+		t.Fatalf("unexpected watch result: %v", list)
+	}
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatal("watch channel not closed")
+	}
+
+	if err := r.Register(context.Background(), a); err != discover.ErrorReadOnly {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
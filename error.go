@@ -0,0 +1,24 @@
+package linker
+
+import "errors"
+
+// error
+var (
+	ErrorWriteQueueFull     = errors.New("linker: write queue is full")
+	ErrorConnectionClosed   = errors.New("linker: connection is closed")
+	ErrorConnectionNotFound = errors.New("linker: connection not found")
+)
+
+// StatusError 携带响应状态码的错误
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func NewStatusError(code int, message string) *StatusError {
+	return &StatusError{Code: code, Message: message}
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
@@ -22,8 +22,8 @@ func main() {
 				graphql.GraphQL(),
 				graphql.Pretty(),
 				graphql.DialOptions(
-					client.InitialCapacity(1),
-					client.MaxCapacity(1),
+					client.MinConns(1),
+					client.MaxConns(1),
 					client.WithOnError(func(err error) {
 						fmt.Println(err.Error())
 					}),
@@ -0,0 +1,115 @@
+package linker
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 客户端在心跳请求中提出的心跳间隔，服务端在心跳响应中返回协商后的结果，单位毫秒
+	HeartbeatIntervalProperty = "heartbeat-interval"
+	// 服务端主动发送的心跳数据包带有该请求属性，客户端需要回复心跳
+	PingProperty = "ping"
+)
+
+// 连接最后一次收到数据包的时间
+func (c *Connection) touch() {
+	atomic.StoreInt64(&c.lastSeen, time.Now().UnixNano())
+}
+
+func (c *Connection) LastSeen() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.lastSeen))
+}
+
+// HeartbeatInterval 连接协商后的心跳间隔
+func (c *Connection) HeartbeatInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.heartbeat))
+}
+
+func (c *Connection) setHeartbeatInterval(d time.Duration) {
+	atomic.StoreInt64(&c.heartbeat, int64(d))
+}
+
+// ping 服务端主动向客户端发送心跳
+func (c *Connection) ping() error {
+	p, err := NewPacket(OperatorHeartbeat, time.Now().UnixNano(), []byte(PingProperty+"=1;"), nil, c.options.pluginForPacketSender)
+	if err != nil {
+		return err
+	}
+
+	return c.writer.Write(p.Bytes())
+}
+
+// negotiateHeartbeat 根据客户端提出的心跳间隔协商连接的心跳间隔，结果限制在服务端允许的范围内
+func (s *Server) negotiateHeartbeat(ctx Context) {
+	c := ctx.Connection()
+	if c == nil || s.options.heartbeatInterval <= 0 {
+		return
+	}
+
+	d := c.HeartbeatInterval()
+	if v, err := strconv.ParseInt(ctx.GetRequestProperty(HeartbeatIntervalProperty), 10, 64); err == nil && v > 0 {
+		d = time.Duration(v) * time.Millisecond
+		if s.options.minHeartbeatInterval > 0 && d < s.options.minHeartbeatInterval {
+			d = s.options.minHeartbeatInterval
+		}
+
+		if s.options.maxHeartbeatInterval > 0 && d > s.options.maxHeartbeatInterval {
+			d = s.options.maxHeartbeatInterval
+		}
+
+		c.setHeartbeatInterval(d)
+	}
+
+	ctx.SetResponseProperty(HeartbeatIntervalProperty, strconv.FormatInt(int64(d/time.Millisecond), 10))
+}
+
+// watchIdle 检测连接是否空闲，超过一个心跳间隔没有收到数据时主动发送心跳，
+// 连续tolerance个心跳间隔没有收到数据时认为连接已经失效，执行OnIdle并断开连接
+func (s *Server) watchIdle(ctx Context, c *Connection, done <-chan struct{}) {
+	if s.options.heartbeatInterval <= 0 {
+		return
+	}
+
+	tolerance := s.options.heartbeatTolerance
+	if tolerance <= 0 {
+		tolerance = 1
+	}
+
+	timer := time.NewTimer(c.HeartbeatInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-timer.C:
+		}
+
+		interval := c.HeartbeatInterval()
+		idle := time.Since(c.LastSeen())
+
+		if idle >= interval*time.Duration(tolerance) {
+			if s.options.idleHandler != nil {
+				// handler中调用Success或者Error会退出当前协程，需要在单独的协程中执行
+				finished := make(chan struct{})
+				go func() {
+					defer close(finished)
+					s.options.idleHandler.Handle(ctx)
+				}()
+				<-finished
+			}
+
+			_ = c.Close()
+			return
+		}
+
+		if idle >= interval {
+			_ = c.ping()
+			timer.Reset(interval)
+		} else {
+			timer.Reset(interval - idle)
+		}
+	}
+}
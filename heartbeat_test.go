@@ -0,0 +1,73 @@
+package linker_test
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+// readPings 读取服务端发送的心跳，answer为true时回复心跳，返回连接断开之前收到的心跳数
+func readPings(t *testing.T, address string, answer bool, timeout time.Duration) (pings int, closed bool) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		_ = conn.SetReadDeadline(deadline)
+		rp, err := linker.ReadPacket(conn, nil)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return pings, false
+			}
+
+			return pings, true
+		}
+
+		if rp.Operator != linker.OperatorHeartbeat || !strings.Contains(string(rp.Header), linker.PingProperty+"=1;") {
+			continue
+		}
+
+		pings++
+		if answer {
+			p, err := linker.NewPacket(linker.OperatorHeartbeat, rp.Sequence, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := conn.Write(p.Bytes()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestIdleDisconnect(t *testing.T) {
+	var idle int64
+	_, address := servertest.Start(t, linker.NewRouter(),
+		linker.HeartbeatInterval(50*time.Millisecond),
+		linker.HeartbeatTolerance(3),
+		linker.WithOnIdle(linker.HandlerFunc(func(ctx linker.Context) {
+			atomic.AddInt64(&idle, 1)
+		})),
+	)
+
+	// 连续3个心跳间隔没有收到数据时断开连接，断开之前发送心跳
+	pings, closed := readPings(t, address, false, time.Second)
+	if !closed || pings < 2 || atomic.LoadInt64(&idle) != 1 {
+		t.Fatalf("unexpected result: closed %v, pings %d, idle %d", closed, pings, idle)
+	}
+
+	// 回复心跳的连接不会被断开
+	pings, closed = readPings(t, address, true, 400*time.Millisecond)
+	if closed || pings < 4 || atomic.LoadInt64(&idle) != 1 {
+		t.Fatalf("unexpected result: closed %v, pings %d, idle %d", closed, pings, idle)
+	}
+}
@@ -2,23 +2,18 @@ package linker
 
 import (
 	"net"
-	"sync"
 
 	"github.com/gorilla/websocket"
 )
 
-// fix panic as websocket concurrency write
+// websocket连接的所有写操作都交给写协程串行处理，避免并发写导致panic
 type webSocketConn struct {
-	mutex sync.Mutex
-	conn  *websocket.Conn
+	conn   *websocket.Conn
+	writer *connWriter
 }
 
-func (ws *webSocketConn) WriteMessage(messageType int, data []byte) error {
-	ws.mutex.Lock()
-	err := ws.conn.WriteMessage(messageType, data)
-	ws.mutex.Unlock()
-
-	return err
+func (ws *webSocketConn) WriteMessage(data []byte) error {
+	return ws.writer.Write(data)
 }
 
 func (ws *webSocketConn) LocalAddr() net.Addr {
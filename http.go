@@ -1,164 +1,146 @@
 package linker
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	uuid "github.com/satori/go.uuid"
 
 	"github.com/gorilla/websocket"
-	"github.com/wpajqz/linker/utils/convert"
 )
 
-func (s *Server) handleWebSocketConnection(conn *websocket.Conn) error {
-	wsn := &webSocketConn{mutex: sync.Mutex{}, conn: conn}
-	var ctx = &ContextWebsocket{Conn: wsn}
+func (s *Server) handleWebSocketConnection(conn *websocket.Conn, tlsState *tls.ConnectionState) error {
+	// 准入判断需要先读取客户端发送的第一个数据包
+	var first *Packet
+	if s.options.acceptHandler != nil {
+		rp, err := s.readWebSocketPacket(conn)
+		if err != nil {
+			_ = conn.Close()
+			return err
+		}
+
+		info := newConnInfo(NetworkWebSocket, conn.LocalAddr().String(), conn.RemoteAddr().String(), tlsState, rp)
+		if p, err := s.accept(info, rp); err != nil {
+			if p != nil {
+				_ = conn.WriteMessage(websocket.BinaryMessage, p.Bytes())
+			}
+
+			_ = conn.Close()
+			return nil
+		}
+
+		first = &rp
+	}
+
+	writer := newConnWriter(s.options, func(frames [][]byte) error {
+		if err := conn.SetWriteDeadline(s.options.writeDeadline()); err != nil {
+			return err
+		}
+
+		// websocket以消息为边界，每个数据包对应一条消息
+		for _, frame := range frames {
+			if err := conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, conn.Close)
+
+	id := uuid.NewV4().String()
+	connection := newConnection(id, NetworkWebSocket, conn.LocalAddr(), conn.RemoteAddr(), s.options, writer, conn.Close)
+	s.connections.add(connection)
+
+	wsn := &webSocketConn{conn: conn, writer: writer}
+	var ctx = &ContextWebsocket{common: common{Context: context.Background(), options: s.options, connection: connection}, Conn: wsn}
+	ctx.Set(nodeID, id)
 
 	if s.options.constructHandler != nil {
 		s.options.constructHandler.Handle(ctx)
 	}
 
-	ctx.Set(nodeID, uuid.NewV4().String())
+	// 每个请求都基于连接的context创建，请求中通过Set保存的数据不会带到后续的请求中
+	base := ctx.Context
 
 	defer func() {
 		if s.options.destructHandler != nil {
 			s.options.destructHandler.Handle(ctx)
 		}
 
+		s.connections.remove(id)
+
 		if err := ctx.UnSubscribeAll(); err != nil {
 			ctx.Error(StatusInternalServerError, err.Error())
 		}
 
+		writer.Close()
 		_ = conn.Close()
 	}()
 
-	var (
-		bType         = make([]byte, 4)
-		bSequence     = make([]byte, 8)
-		bHeaderLength = make([]byte, 4)
-		bBodyLength   = make([]byte, 4)
-		sequence      int64
-		headerLength  uint32
-		bodyLength    uint32
-	)
+	idle := make(chan struct{})
+	defer close(idle)
+	go s.watchIdle(ctx, connection, idle)
 
 	for {
-		if s.options.timeout != 0 {
-			err := conn.SetReadDeadline(time.Now().Add(s.options.timeout))
+		var rp Packet
+		if first != nil {
+			rp, first = *first, nil
+		} else {
+			var err error
+			rp, err = s.readWebSocketPacket(conn)
 			if err != nil {
 				return err
 			}
-
-			err = conn.SetWriteDeadline(time.Now().Add(s.options.timeout))
-			if err != nil {
-				return err
-			}
-		}
-
-		_, r, err := conn.NextReader()
-		if err != nil {
-			return err
-		}
-
-		if _, err := io.ReadFull(r, bType); err != nil {
-			return err
-		}
-
-		if _, err := io.ReadFull(r, bSequence); err != nil {
-			return err
-		}
-
-		if _, err := io.ReadFull(r, bHeaderLength); err != nil {
-			return err
 		}
 
-		if _, err := io.ReadFull(r, bBodyLength); err != nil {
-			return err
-		}
-
-		sequence = convert.BytesToInt64(bSequence)
-		headerLength = convert.BytesToUint32(bHeaderLength)
-		bodyLength = convert.BytesToUint32(bBodyLength)
-
-		header := make([]byte, headerLength)
-		if _, err := io.ReadFull(r, header); err != nil {
-			return err
+		connection.touch()
 
+		if rp.Operator == OperatorCancel {
+			connection.cancel(rp.Sequence)
+			continue
 		}
 
-		body := make([]byte, bodyLength)
-		if _, err := io.ReadFull(r, body); err != nil {
-			return err
-		}
+		// 请求使用单独的context，ctx始终是连接的context，连接断开时的清理不依赖最后一个请求
+		pctx := NewContextWebsocket(base, wsn, rp.Operator, rp.Sequence, rp.Header, rp.Body, s.options)
+		pctx.connection = connection
+		pctx.cancelCtx = connection.track(rp.Sequence)
 
-		rp, err := NewPacket(convert.BytesToUint32(bType), sequence, header, body, s.options.pluginForPacketReceiver)
+		accepted := s.dispatch(connection.limiter, s.options.inOrder, pctx, func() {
+			defer connection.untrack(rp.Sequence)
+			s.handleWebSocketPacket(pctx, rp)
+		})
 
-		if err != nil {
-			return err
+		if !accepted {
+			connection.untrack(rp.Sequence)
 		}
-
-		ctx = NewContextWebsocket(ctx.Context, wsn, rp.Operator, rp.Sequence, rp.Header, rp.Body, s.options)
-		go s.handleWebSocketPacket(ctx, conn, rp)
 	}
 }
 
-func (s *Server) handleWebSocketPacket(ctx Context, conn *websocket.Conn, rp Packet) {
-	defer func() {
-		if r := recover(); r != nil {
-			var errMsg string
-
-			switch v := r.(type) {
-			case string:
-				errMsg = v
-			case error:
-				errMsg = v.Error()
-			default:
-				errMsg = StatusText(StatusInternalServerError)
-			}
-
-			ctx.Set(errorTag, errMsg)
-
-			if s.options.errorHandler != nil {
-				s.options.errorHandler.Handle(ctx)
-			}
-
-			ctx.Error(StatusInternalServerError, errMsg)
-		}
-	}()
-
-	if rp.Operator == OperatorHeartbeat {
-		if s.options.pingHandler != nil {
-			s.options.pingHandler.Handle(ctx)
+func (s *Server) readWebSocketPacket(conn *websocket.Conn) (Packet, error) {
+	if s.options.timeout != 0 {
+		err := conn.SetReadDeadline(time.Now().Add(s.options.timeout))
+		if err != nil {
+			return Packet{}, err
 		}
-
-		ctx.Success(nil)
 	}
 
-	handler, ok := s.router.handlerContainer[rp.Operator]
-	if !ok {
-		ctx.Error(StatusInternalServerError, "server don't register your request.")
+	_, r, err := conn.NextReader()
+	if err != nil {
+		return Packet{}, err
 	}
 
-	if rm, ok := s.router.routerMiddleware[rp.Operator]; ok {
-		for _, v := range rm {
-			ctx = v.Handle(ctx)
-		}
-	}
-
-	for _, v := range s.router.middleware {
-		ctx = v.Handle(ctx)
-		if tm, ok := v.(TerminateMiddleware); ok {
-			tm.Terminate(ctx)
-		}
-	}
+	return ReadPacket(r, s.options.pluginForPacketReceiver)
+}
 
-	handler.Handle(ctx)
-	ctx.Success(nil) // If it don't call the function of Success or Error, deal it by default
+func (s *Server) handleWebSocketPacket(ctx Context, rp Packet) {
+	s.serve(ctx, rp)
 }
 
 // runHTTP 开始运行HTTP服务
@@ -182,12 +164,12 @@ func (s *Server) runHTTP(address, wsRoute string, handler http.Handler) error {
 				return
 			}
 
-			go func(conn *websocket.Conn) {
-				err := s.handleWebSocketConnection(conn)
+			go func(conn *websocket.Conn, tlsState *tls.ConnectionState) {
+				err := s.handleWebSocketConnection(conn, tlsState)
 				if err != nil && err != io.EOF {
 					fmt.Printf("websocket connection error: %s\n", err.Error())
 				}
-			}(conn)
+			}(conn, ctx.Request.TLS)
 		})
 
 		//	match old version
@@ -208,12 +190,12 @@ func (s *Server) runHTTP(address, wsRoute string, handler http.Handler) error {
 				return
 			}
 
-			go func(conn *websocket.Conn) {
-				err := s.handleWebSocketConnection(conn)
+			go func(conn *websocket.Conn, tlsState *tls.ConnectionState) {
+				err := s.handleWebSocketConnection(conn, tlsState)
 				if err != nil && err != io.EOF {
 					fmt.Printf("websocket connection error: %s\n", err.Error())
 				}
-			}(conn)
+			}(conn, ctx.Request.TLS)
 		})
 	case nil:
 		http.HandleFunc(wsRoute, func(w http.ResponseWriter, r *http.Request) {
@@ -233,18 +215,35 @@ func (s *Server) runHTTP(address, wsRoute string, handler http.Handler) error {
 				return
 			}
 
-			go func(conn *websocket.Conn) {
-				err := s.handleWebSocketConnection(conn)
+			go func(conn *websocket.Conn, tlsState *tls.ConnectionState) {
+				err := s.handleWebSocketConnection(conn, tlsState)
 				if err != nil && err != io.EOF {
 					fmt.Printf("websocket connection error: %s\n", err.Error())
 				}
-			}(conn)
+			}(conn, r.TLS)
 		})
 	default:
 		return errors.New("unsupported http's handler")
 	}
 
+	listener, err := net.Listen(NetworkTCP, address)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: handler}
+	s.onShutdown(srv.Shutdown, nil)
+
 	fmt.Printf("Listening and serving HTTP on %s\n", address)
 
-	return http.ListenAndServe(address, handler)
+	if err := s.register(NetworkWebSocket, listener.Addr(), map[string]string{MetadataWSRoute: wsRoute}); err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
 }
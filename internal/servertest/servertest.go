@@ -0,0 +1,110 @@
+package servertest
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+)
+
+const timeout = 5 * time.Second
+
+// Address 获取一个空闲的本地地址
+func Address(t testing.TB) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+// Start 在空闲的地址上启动tcp服务，返回服务以及访问地址
+func Start(t testing.TB, router *linker.Router, opts ...linker.Option) (*linker.Server, string) {
+	t.Helper()
+
+	address := Address(t)
+	s := linker.NewServer(append(opts, linker.WithTCPEndpoint(linker.Endpoint{Address: address}))...)
+	if router != nil {
+		s.BindRouter(router)
+	}
+
+	Run(t, s, address)
+
+	return s, address
+}
+
+// Run 在后台运行服务，address可以连接以后返回，测试结束时关闭服务
+func Run(t testing.TB, s *linker.Server, address string) {
+	t.Helper()
+
+	var err error
+	stopped := make(chan struct{})
+	go func() {
+		err = s.Run()
+		close(stopped)
+	}()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("servertest: shutdown: %v", err)
+		}
+
+		select {
+		case <-stopped:
+		case <-time.After(timeout):
+			t.Error("servertest: server not stopped")
+		}
+	})
+
+	deadline := time.Now().Add(timeout)
+	for {
+		select {
+		case <-stopped:
+			t.Fatalf("servertest: run: %v", err)
+		default:
+		}
+
+		conn, derr := net.DialTimeout("tcp", address, 100*time.Millisecond)
+		if derr == nil {
+			probe(conn)
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("servertest: server not ready: %v", derr)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 等待探测连接被服务端移除，避免影响连接数
+	for s.Connections().Count() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// probe 发送心跳并等待服务端处理完成，无论成功与否，服务端都已经登记或者拒绝了该连接
+func probe(conn net.Conn) {
+	defer conn.Close()
+
+	p, err := linker.NewPacket(linker.OperatorHeartbeat, 0, nil, nil, nil)
+	if err != nil {
+		return
+	}
+
+	if _, err := conn.Write(p.Bytes()); err != nil {
+		return
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _ = linker.ReadPacket(conn, nil)
+}
@@ -0,0 +1,83 @@
+package linker
+
+import "sync/atomic"
+
+// 并发请求数超过限制时的处理方式
+type OverloadPolicy int
+
+const (
+	OverloadQueue  OverloadPolicy = iota // 暂停读取新的数据包，直到有空闲的处理协程，暂停期间也无法读取取消帧
+	OverloadReject                       // 直接返回StatusTooManyRequests
+)
+
+// 基于信号量的并发限制，nil表示不限制
+type limiter chan struct{}
+
+func newLimiter(n int) limiter {
+	if n <= 0 {
+		return nil
+	}
+
+	return make(limiter, n)
+}
+
+func (l limiter) acquire(block bool) bool {
+	if l == nil {
+		return true
+	}
+
+	if block {
+		l <- struct{}{}
+		return true
+	}
+
+	select {
+	case l <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l limiter) release() {
+	if l != nil {
+		<-l
+	}
+}
+
+// dispatch 在连接和全局并发限制内调度请求处理，ordered为true时等待当前请求处理完成后才返回，
+// 请求被拒绝时返回false
+func (s *Server) dispatch(conn limiter, ordered bool, ctx Context, handle func()) bool {
+	block := s.options.overloadPolicy == OverloadQueue
+
+	if !conn.acquire(block) {
+		go ctx.Error(StatusTooManyRequests, StatusText(StatusTooManyRequests))
+		return false
+	}
+
+	if !s.workers.acquire(block) {
+		conn.release()
+		go ctx.Error(StatusTooManyRequests, StatusText(StatusTooManyRequests))
+		return false
+	}
+
+	atomic.AddInt64(&s.requests, 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer func() {
+			s.workers.release()
+			conn.release()
+			atomic.AddInt64(&s.requests, -1)
+			close(done)
+		}()
+
+		handle()
+	}()
+
+	if ordered {
+		<-done
+	}
+
+	return true
+}
@@ -0,0 +1,115 @@
+package linker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/export"
+	"github.com/wpajqz/linker/codec"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+// resultCallback 把请求结果的状态码写入result，0表示成功
+type resultCallback struct {
+	result chan int
+}
+
+func (rc resultCallback) OnSuccess(header, body []byte) {
+	rc.result <- 0
+}
+
+func (rc resultCallback) OnError(status int, message string) {
+	rc.result <- status
+}
+
+func (rc resultCallback) OnStart() {}
+
+func (rc resultCallback) OnEnd() {}
+
+// sleepClient 启动处理/sleep请求的服务，返回连接到服务的客户端，finished按照完成顺序接收请求参数
+func sleepClient(t *testing.T, opts ...linker.Option) (*export.Client, <-chan time.Duration) {
+	finished := make(chan time.Duration, 8)
+	router := linker.NewRouter()
+	router.Route("/sleep", linker.HandlerFunc(func(ctx linker.Context) {
+		var d time.Duration
+		_ = ctx.ParseParam(&d)
+		time.Sleep(d)
+
+		finished <- d
+		ctx.Success(nil)
+	}))
+
+	_, address := servertest.Start(t, router, opts...)
+
+	c, err := export.NewClient(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = c.Close() })
+
+	c.SetContentType(codec.JSON)
+
+	return c, finished
+}
+
+// sendAll 同时发送所有请求，按照发送顺序返回状态码
+func sendAll(t *testing.T, c *export.Client, durations ...time.Duration) []int {
+	results := make([]chan int, len(durations))
+	for i, d := range durations {
+		results[i] = make(chan int, 1)
+		if err := c.AsyncSend("/sleep", d, resultCallback{results[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	codes := make([]int, len(durations))
+	for i, result := range results {
+		select {
+		case codes[i] = <-result:
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout waiting for result")
+		}
+	}
+
+	return codes
+}
+
+func TestOverloadReject(t *testing.T) {
+	c, _ := sleepClient(t, linker.MaxConcurrentRequestsPerConn(1), linker.Overload(linker.OverloadReject))
+
+	codes := sendAll(t, c, 100*time.Millisecond, time.Millisecond)
+	if codes[0] != 0 || codes[1] != linker.StatusTooManyRequests {
+		t.Fatalf("unexpected codes: %v", codes)
+	}
+}
+
+func TestOverloadQueue(t *testing.T) {
+	c, _ := sleepClient(t, linker.MaxConcurrentRequestsPerConn(1), linker.Overload(linker.OverloadQueue))
+
+	start := time.Now()
+	codes := sendAll(t, c, 100*time.Millisecond, 100*time.Millisecond)
+	if codes[0] != 0 || codes[1] != 0 {
+		t.Fatalf("unexpected codes: %v", codes)
+	}
+
+	// 超过限制的请求排队等待，不会同时处理
+	if time.Since(start) < 200*time.Millisecond {
+		t.Fatalf("requests should be queued: %s", time.Since(start))
+	}
+}
+
+func TestInOrder(t *testing.T) {
+	c, finished := sleepClient(t, linker.InOrder())
+
+	codes := sendAll(t, c, 100*time.Millisecond, time.Millisecond)
+	if codes[0] != 0 || codes[1] != 0 {
+		t.Fatalf("unexpected codes: %v", codes)
+	}
+
+	// 较慢的请求先到达，也先处理完成
+	if first, second := <-finished, <-finished; first != 100*time.Millisecond || second != time.Millisecond {
+		t.Fatalf("unexpected order: %s, %s", first, second)
+	}
+}
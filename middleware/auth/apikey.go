@@ -0,0 +1,53 @@
+package auth
+
+import "sync"
+
+type (
+	// KeyStore API key的存储，key不存在时返回ErrorInvalidKey
+	KeyStore interface {
+		Lookup(key string) (Claims, error)
+	}
+
+	// MemoryKeyStore 保存在内存中的API key
+	MemoryKeyStore struct {
+		keys sync.Map
+	}
+
+	// APIKey 使用KeyStore校验API key
+	APIKey struct {
+		store KeyStore
+	}
+)
+
+var (
+	_ KeyStore      = new(MemoryKeyStore)
+	_ Authenticator = new(APIKey)
+)
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{}
+}
+
+func (ms *MemoryKeyStore) Add(key string, claims Claims) {
+	ms.keys.Store(key, claims)
+}
+
+func (ms *MemoryKeyStore) Remove(key string) {
+	ms.keys.Delete(key)
+}
+
+func (ms *MemoryKeyStore) Lookup(key string) (Claims, error) {
+	if v, ok := ms.keys.Load(key); ok {
+		return v.(Claims), nil
+	}
+
+	return nil, ErrorInvalidKey
+}
+
+func NewAPIKey(store KeyStore) *APIKey {
+	return &APIKey{store: store}
+}
+
+func (a *APIKey) Authenticate(credential string) (Claims, error) {
+	return a.store.Lookup(credential)
+}
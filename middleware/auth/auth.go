@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"time"
+
+	"github.com/wpajqz/linker"
+)
+
+// 认证结果在Context中保存的key
+const ClaimsKey = linker.ClaimsKey
+
+// 按照连接认证时，认证结果在连接上保存的key
+const sessionKey = "auth_session"
+
+type (
+	// Claims 认证通过后得到的身份信息，linker.RequireScopes等访问策略依赖该类型
+	Claims = linker.Claims
+
+	// Authenticator 校验凭证并返回身份信息
+	Authenticator interface {
+		Authenticate(credential string) (Claims, error)
+	}
+
+	// Auth 认证中间件，认证失败时返回StatusUnauthorized
+	Auth struct {
+		options        Options
+		authenticators []Authenticator
+	}
+
+	// session 连接上缓存的认证结果，expiresAt为零值时不会过期
+	session struct {
+		claims    Claims
+		expiresAt time.Time
+	}
+)
+
+var _ linker.Middleware = new(Auth)
+
+func New(opts ...Option) (*Auth, error) {
+	options := Options{
+		property: "Authorization",
+		rsaKeys:  make(map[string]*rsa.PublicKey),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.jwksFile != "" {
+		keys, err := LoadJWKS(options.jwksFile)
+		if err != nil {
+			return nil, err
+		}
+
+		for kid, key := range keys {
+			options.rsaKeys[kid] = key
+		}
+	}
+
+	var authenticators []Authenticator
+	if options.hmacSecret != nil || len(options.rsaKeys) > 0 {
+		authenticators = append(authenticators, NewJWT(options.hmacSecret, options.rsaKeys))
+	}
+
+	if options.keyStore != nil {
+		authenticators = append(authenticators, NewAPIKey(options.keyStore))
+	}
+
+	authenticators = append(authenticators, options.authenticators...)
+	if len(authenticators) == 0 {
+		return nil, ErrorNoAuthenticator
+	}
+
+	return &Auth{options: options, authenticators: authenticators}, nil
+}
+
+func (a *Auth) Handle(ctx linker.Context) linker.Context {
+	conn := ctx.Connection()
+	if a.options.perConnection && conn != nil {
+		// 缓存的认证结果过期以后需要重新认证
+		if s, ok := conn.Get(sessionKey).(session); ok && (s.expiresAt.IsZero() || time.Now().Before(s.expiresAt)) {
+			ctx.Set(ClaimsKey, s.claims)
+			return ctx
+		}
+	}
+
+	credential := ctx.GetRequestProperty(a.options.property)
+	if credential == "" {
+		ctx.Error(linker.StatusUnauthorized, ErrorCredentialMissing.Error())
+	}
+
+	claims, err := a.Authenticate(credential)
+	if err != nil {
+		ctx.Error(linker.StatusUnauthorized, err.Error())
+	}
+
+	ctx.Set(ClaimsKey, claims)
+	if a.options.perConnection && conn != nil {
+		conn.Set(sessionKey, session{claims: claims, expiresAt: claims.ExpiresAt()})
+	}
+
+	return ctx
+}
+
+// Authenticate 依次尝试配置的认证方式，返回第一个认证通过的结果，
+// 只配置了一种认证方式时返回该方式的错误，否则返回包含所有错误的Errors
+func (a *Auth) Authenticate(credential string) (Claims, error) {
+	var errs Errors
+	for _, v := range a.authenticators {
+		claims, err := v.Authenticate(credential)
+		if err == nil {
+			return claims, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+
+	return nil, errs
+}
+
+// GetClaims 获取认证中间件保存在Context中的身份信息
+func GetClaims(ctx linker.Context) Claims {
+	claims, _ := ctx.Get(ClaimsKey).(Claims)
+	return claims
+}
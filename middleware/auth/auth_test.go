@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/export"
+	"github.com/wpajqz/linker/codec"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+func TestHMAC(t *testing.T) {
+	secret := []byte("secret")
+	a, err := New(HMAC(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "u1",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := a.Authenticate("Bearer " + token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims["sub"] != "u1" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+
+	if _, err := a.Authenticate(token + "x"); err == nil {
+		t.Error("tampered token should be rejected")
+	}
+
+	expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	}).SignedString(secret)
+	if _, err := a.Authenticate(expired); err == nil {
+		t.Error("expired token should be rejected")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data, _ := json.Marshal(set)
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(JWKSFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "u2"})
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := a.Authenticate(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims["sub"] != "u2" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+
+	token.Header["kid"] = "k2"
+	signed, _ = token.SignedString(key)
+	if _, err := a.Authenticate(signed); err == nil {
+		t.Error("unknown kid should be rejected")
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	store := NewMemoryKeyStore()
+	store.Add("key1", Claims{"sub": "service"})
+
+	a, err := New(APIKeys(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := a.Authenticate("key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims["sub"] != "service" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+
+	if _, err := a.Authenticate("key2"); err != ErrorInvalidKey {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+type authenticatorFunc func(credential string) (Claims, error)
+
+func (fn authenticatorFunc) Authenticate(credential string) (Claims, error) {
+	return fn(credential)
+}
+
+func TestErrors(t *testing.T) {
+	a, err := New(HMAC([]byte("secret")), APIKeys(NewMemoryKeyStore()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = a.Authenticate("unknown")
+	if errs, ok := err.(Errors); !ok || len(errs) != 2 || !errors.Is(err, ErrorInvalidKey) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// 错误信息作为响应header的属性值，不能包含分隔符
+	if strings.Contains(err.Error(), ";") {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+}
+
+func TestPerConnectionExpiry(t *testing.T) {
+	var count int64
+	a, err := New(PerConnection(), Authenticators(authenticatorFunc(func(credential string) (Claims, error) {
+		if credential != "token" {
+			return nil, ErrorInvalidToken
+		}
+
+		atomic.AddInt64(&count, 1)
+		exp := time.Now().Add(200 * time.Millisecond)
+		return Claims{"sub": "u1", "exp": float64(exp.UnixNano()) / 1e9}, nil
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := linker.NewRouter()
+	router.Use(a)
+	router.Route("/whoami", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Success(GetClaims(ctx)["sub"])
+	}))
+
+	_, address := servertest.Start(t, router)
+
+	c, err := export.NewClient(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.SetContentType(codec.JSON)
+
+	call := func() int {
+		code := 0
+		err := c.SyncSend("/whoami", nil, &callback{onError: func(status int) {
+			code = status
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return code
+	}
+
+	c.SetRequestProperty("Authorization", "token")
+	if code := call(); code != 0 {
+		t.Fatalf("unexpected code %d", code)
+	}
+
+	// 连接上缓存的认证结果在过期之前不需要凭证
+	c.SetRequestProperty("Authorization", "")
+	if code := call(); code != 0 || atomic.LoadInt64(&count) != 1 {
+		t.Fatalf("unexpected code %d, authenticated %d times", code, count)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if code := call(); code != linker.StatusUnauthorized {
+		t.Fatalf("expired claims should be rejected, code %d", code)
+	}
+
+	c.SetRequestProperty("Authorization", "token")
+	if code := call(); code != 0 || atomic.LoadInt64(&count) != 2 {
+		t.Fatalf("unexpected code %d, authenticated %d times", code, count)
+	}
+}
+
+type callback struct {
+	onError func(status int)
+}
+
+func (cb *callback) OnSuccess(header, body []byte) {}
+
+func (cb *callback) OnError(status int, message string) {
+	cb.onError(status)
+}
+
+func (cb *callback) OnStart() {}
+
+func (cb *callback) OnEnd() {}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+)
+
+// error
+var (
+	ErrorCredentialMissing = errors.New("credential missing")
+	ErrorInvalidToken      = errors.New("invalid token")
+	ErrorInvalidKey        = errors.New("invalid api key")
+	ErrorUnknownKeyID      = errors.New("unknown key id")
+	ErrorNoAuthenticator   = errors.New("no authenticator configured")
+)
+
+// Errors 所有认证方式都失败时的错误，按照尝试顺序包含每一种认证方式的错误
+type Errors []error
+
+func (e Errors) Error() string {
+	list := make([]string, 0, len(e))
+	for _, err := range e {
+		list = append(list, err.Error())
+	}
+
+	// 错误信息会写入响应的header，不能使用header的分隔符;
+	return strings.Join(list, ", ")
+}
+
+// Is 任意一种认证方式的错误匹配target时返回true
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
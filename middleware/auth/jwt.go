@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type (
+	// JWT 使用HMAC密钥或者RSA公钥校验token
+	JWT struct {
+		secret []byte
+		keys   map[string]*rsa.PublicKey
+	}
+
+	jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+)
+
+var _ Authenticator = new(JWT)
+
+func NewJWT(secret []byte, keys map[string]*rsa.PublicKey) *JWT {
+	return &JWT{secret: secret, keys: keys}
+}
+
+func (j *JWT) Authenticate(credential string) (Claims, error) {
+	credential = strings.TrimPrefix(credential, "Bearer ")
+
+	var claims jwt.MapClaims
+	token, err := jwt.ParseWithClaims(credential, &claims, j.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, ErrorInvalidToken
+	}
+
+	return Claims(claims), nil
+}
+
+func (j *JWT) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if j.secret == nil {
+			return nil, ErrorInvalidToken
+		}
+
+		return j.secret, nil
+	case *jwt.SigningMethodRSA:
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := j.keys[kid]; ok {
+			return key, nil
+		}
+
+		return nil, ErrorUnknownKeyID
+	default:
+		return nil, ErrorInvalidToken
+	}
+}
+
+// LoadJWKS 从本地文件加载JWKS中的RSA公钥，返回kid到公钥的映射
+func LoadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
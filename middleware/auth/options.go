@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/rsa"
+)
+
+type (
+	Options struct {
+		property       string
+		perConnection  bool
+		hmacSecret     []byte
+		rsaKeys        map[string]*rsa.PublicKey
+		jwksFile       string
+		keyStore       KeyStore
+		authenticators []Authenticator
+	}
+
+	Option func(o *Options)
+)
+
+// 读取凭证的请求属性，默认Authorization
+func Property(name string) Option {
+	return func(o *Options) {
+		o.property = name
+	}
+}
+
+// 每个连接只认证一次，认证结果缓存在连接上供后续请求使用，身份信息中的exp过期以后重新认证
+func PerConnection() Option {
+	return func(o *Options) {
+		o.perConnection = true
+	}
+}
+
+// 使用HMAC密钥校验JWT
+func HMAC(secret []byte) Option {
+	return func(o *Options) {
+		o.hmacSecret = secret
+	}
+}
+
+// 使用RSA公钥校验JWT，kid为空时匹配所有没有kid的token
+func RSA(kid string, key *rsa.PublicKey) Option {
+	return func(o *Options) {
+		o.rsaKeys[kid] = key
+	}
+}
+
+// 从本地JWKS文件加载RSA公钥
+func JWKSFile(path string) Option {
+	return func(o *Options) {
+		o.jwksFile = path
+	}
+}
+
+// 使用API key认证
+func APIKeys(store KeyStore) Option {
+	return func(o *Options) {
+		o.keyStore = store
+	}
+}
+
+// 自定义认证方式，在内置认证方式之后依次尝试
+func Authenticators(authenticators ...Authenticator) Option {
+	return func(o *Options) {
+		o.authenticators = append(o.authenticators, authenticators...)
+	}
+}
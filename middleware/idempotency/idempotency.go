@@ -0,0 +1,163 @@
+package idempotency
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wpajqz/linker"
+)
+
+// 响应来自缓存时带有该响应属性
+const Replayed = "idempotent-replayed"
+
+// defaultScope 使用认证身份中的sub作为幂等键的作用范围，没有认证时只使用幂等键，
+// 客户端重试通常发生在新的连接上，作用范围不能依赖连接
+func defaultScope(ctx linker.Context) string {
+	if claims, ok := ctx.Get(linker.ClaimsKey).(linker.Claims); ok {
+		if sub, ok := claims["sub"].(string); ok && sub != "" {
+			return "sub:" + sub
+		}
+	}
+
+	return ""
+}
+
+type (
+	// entry 幂等键对应的处理结果，done关闭以后结果可用，正在处理的结果在expires之后也会被清理
+	entry struct {
+		once    sync.Once
+		done    chan struct{}
+		success bool
+		body    interface{}
+		code    int
+		message string
+		expires time.Time
+	}
+
+	// Idempotency 幂等中间件，按照作用范围、请求类型和幂等键缓存响应，窗口期内重复的请求直接返回缓存的结果，
+	// 需要放在认证、限流等中间件之后，只有成功的响应和确定的客户端错误会被缓存
+	Idempotency struct {
+		options Options
+		mu      sync.Mutex
+		entries map[string]*entry
+		swept   time.Time
+	}
+
+	// recorder 记录第一次请求的处理结果
+	recorder struct {
+		linker.Context
+		idempotency *Idempotency
+		key         string
+		entry       *entry
+	}
+)
+
+var _ linker.Middleware = new(Idempotency)
+
+func New(opts ...Option) *Idempotency {
+	options := Options{
+		window:  5 * time.Minute,
+		maxWait: 10 * time.Second,
+		scope:   defaultScope,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &Idempotency{options: options, entries: make(map[string]*entry)}
+}
+
+func (m *Idempotency) Handle(ctx linker.Context) linker.Context {
+	key := ctx.GetRequestProperty(linker.IdempotencyKeyProperty)
+	if key == "" || ctx.Operator() == linker.OperatorHeartbeat {
+		return ctx
+	}
+
+	key = m.options.scope(ctx) + ":" + strconv.FormatUint(uint64(ctx.Operator()), 10) + ":" + key
+
+	e, first := m.acquire(key, time.Now())
+	if first {
+		return &recorder{Context: ctx, idempotency: m, key: key, entry: e}
+	}
+
+	// 相同的请求正在处理时等待处理结果
+	select {
+	case <-e.done:
+	case <-time.After(m.options.maxWait):
+		ctx.Error(linker.StatusConflict, "request with the same idempotency key is in progress")
+	}
+
+	ctx.SetResponseProperty(Replayed, "1")
+	if e.success {
+		ctx.Success(e.body)
+	}
+
+	ctx.Error(e.code, e.message)
+
+	return ctx
+}
+
+// acquire 获取幂等键对应的结果，不存在时创建正在处理的结果并返回true，
+// 正在处理的结果最多保留一个窗口期，处理过程中没有响应的请求不会一直占用幂等键
+func (m *Idempotency) acquire(key string, now time.Time) (*entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+	if e, ok := m.entries[key]; ok {
+		return e, false
+	}
+
+	e := &entry{done: make(chan struct{}), expires: now.Add(m.options.window)}
+	m.entries[key] = e
+
+	return e, true
+}
+
+// sweep 清理过期的结果，每秒最多执行一次，调用方需要持有锁
+func (m *Idempotency) sweep(now time.Time) {
+	if now.Sub(m.swept) < time.Second {
+		return
+	}
+
+	m.swept = now
+	for key, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// finish 保存处理结果并唤醒等待的请求，不缓存的结果在唤醒后被删除，之后的重试会重新处理
+func (m *Idempotency) finish(key string, e *entry, success bool, body interface{}, code int, message string) {
+	e.once.Do(func() {
+		e.success, e.body, e.code, e.message = success, body, code, message
+
+		m.mu.Lock()
+		if success || cacheable(code) {
+			e.expires = time.Now().Add(m.options.window)
+		} else if m.entries[key] == e {
+			delete(m.entries, key)
+		}
+		m.mu.Unlock()
+
+		close(e.done)
+	})
+}
+
+func (r *recorder) Success(body interface{}) {
+	r.idempotency.finish(r.key, r.entry, true, body, 0, "")
+	r.Context.Success(body)
+}
+
+func (r *recorder) Error(code int, message string) {
+	r.idempotency.finish(r.key, r.entry, false, nil, code, message)
+	r.Context.Error(code, message)
+}
+
+// cacheable 服务端错误、超时和限流的结果可以通过重试改变，不缓存
+func cacheable(code int) bool {
+	return code < linker.StatusInternalServerError && code != linker.StatusRequestTimeout && code != linker.StatusTooManyRequests
+}
@@ -0,0 +1,185 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+func TestIdempotency(t *testing.T) {
+	var charges int64
+	router := linker.NewRouter()
+	router.Use(New(Window(time.Minute)))
+	router.Route("/charge", linker.HandlerFunc(func(ctx linker.Context) {
+		time.Sleep(100 * time.Millisecond)
+		ctx.Success(atomic.AddInt64(&charges, 1))
+	}))
+	router.Route("/fail", linker.HandlerFunc(func(ctx linker.Context) {
+		atomic.AddInt64(&charges, 1)
+		ctx.Error(linker.StatusServiceUnavailable, "unavailable")
+	}))
+	_, address := servertest.Start(t, router)
+
+	c, err := client.NewClient([]string{address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// 相同幂等键的请求同时到达时只处理一次
+	ctx := client.WithProperty(context.Background(), linker.IdempotencyKeyProperty, "k1")
+	results := make([]int64, 3)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			if err := c.Invoke(ctx, "/charge", nil, &results[i]); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if atomic.LoadInt64(&charges) != 1 || results[0] != 1 || results[1] != 1 || results[2] != 1 {
+		t.Fatalf("unexpected charges: %d, results: %v", charges, results)
+	}
+
+	var result int64
+	if err := c.Invoke(client.WithProperty(context.Background(), linker.IdempotencyKeyProperty, "k2"), "/charge", nil, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt64(&charges) != 2 || result != 2 {
+		t.Fatalf("unexpected charges: %d, result: %d", charges, result)
+	}
+
+	// 服务端错误不缓存，重试时重新处理
+	ctx = client.WithProperty(context.Background(), linker.IdempotencyKeyProperty, "k3")
+	for i := 0; i < 2; i++ {
+		if err := c.Invoke(ctx, "/fail", nil, nil); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	if atomic.LoadInt64(&charges) != 4 {
+		t.Fatalf("unexpected charges: %d", charges)
+	}
+}
+
+// subMiddleware 使用请求属性中的sub作为认证身份
+type subMiddleware struct{}
+
+func (subMiddleware) Handle(ctx linker.Context) linker.Context {
+	if sub := ctx.GetRequestProperty("sub"); sub != "" {
+		ctx.Set(linker.ClaimsKey, linker.Claims{"sub": sub})
+	}
+
+	return ctx
+}
+
+func TestScope(t *testing.T) {
+	var charges int64
+	router := linker.NewRouter()
+	router.Use(subMiddleware{})
+	router.Use(New(Window(time.Minute)))
+	router.Route("/charge", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Success(atomic.AddInt64(&charges, 1))
+	}))
+	_, address := servertest.Start(t, router)
+
+	cases := []struct {
+		sub    string
+		result int64
+	}{
+		{"", 1},
+		{"", 1}, // 没有认证时不同连接上相同的幂等键共用结果
+		{"u1", 2},
+		{"u1", 2},
+		{"u2", 3}, // 不同身份的幂等键互不影响
+	}
+
+	for _, v := range cases {
+		c, err := client.NewClient([]string{address})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := client.WithProperty(context.Background(), linker.IdempotencyKeyProperty, "k1")
+		if v.sub != "" {
+			ctx = client.WithProperty(ctx, "sub", v.sub)
+		}
+
+		var result int64
+		if err := c.Invoke(ctx, "/charge", nil, &result); err != nil {
+			t.Fatal(err)
+		}
+
+		if result != v.result {
+			t.Fatalf("sub %q: unexpected result %d", v.sub, result)
+		}
+
+		_ = c.Close()
+	}
+}
+
+func TestRetryReconnect(t *testing.T) {
+	var charges int64
+	router := linker.NewRouter()
+	router.Use(New(Window(time.Minute)))
+	router.Route("/charge", linker.HandlerFunc(func(ctx linker.Context) {
+		n := atomic.AddInt64(&charges, 1)
+
+		// 第一次处理完成以后连接断开，客户端收不到响应
+		if n == 1 {
+			_ = ctx.Connection().Close()
+		}
+
+		ctx.Success(n)
+	}))
+	_, address := servertest.Start(t, router)
+
+	policy := client.RetryPolicy{MaxAttempts: 3, InitialBackoff: 50 * time.Millisecond}
+	c, err := client.NewClient([]string{address}, client.Retry(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// 重试通过新的连接发送，返回第一次处理的结果
+	var result int64
+	if err := c.Invoke(context.Background(), "/charge", nil, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt64(&charges) != 1 || result != 1 {
+		t.Fatalf("unexpected charges: %d, result: %d", charges, result)
+	}
+}
+
+func TestInflightExpires(t *testing.T) {
+	m := New(Window(time.Minute))
+	now := time.Now()
+
+	e, first := m.acquire("k", now)
+	if !first {
+		t.Fatal("first request should be processed")
+	}
+
+	if v, first := m.acquire("k", now.Add(time.Second)); first || v != e {
+		t.Fatal("request in progress should be reused")
+	}
+
+	// 一直没有响应的请求超过窗口期以后被清理
+	if v, first := m.acquire("k", now.Add(2*time.Minute)); !first || v == e {
+		t.Fatal("request without response should expire")
+	}
+}
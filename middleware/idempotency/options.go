@@ -0,0 +1,38 @@
+package idempotency
+
+import (
+	"time"
+
+	"github.com/wpajqz/linker"
+)
+
+type (
+	Options struct {
+		window  time.Duration
+		maxWait time.Duration
+		scope   func(ctx linker.Context) string
+	}
+
+	Option func(o *Options)
+)
+
+// 响应的缓存时间，默认5分钟
+func Window(d time.Duration) Option {
+	return func(o *Options) {
+		o.window = d
+	}
+}
+
+// 相同幂等键的请求正在处理时最多等待的时间，超时返回StatusConflict，默认10秒
+func MaxWait(d time.Duration) Option {
+	return func(o *Options) {
+		o.maxWait = d
+	}
+}
+
+// 幂等键的作用范围，不同范围内相同的幂等键互不影响，默认使用认证身份中的sub，没有认证时所有请求共用同一个范围
+func Scope(fn func(ctx linker.Context) string) Option {
+	return func(o *Options) {
+		o.scope = fn
+	}
+}
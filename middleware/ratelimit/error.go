@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"errors"
+	"time"
+)
+
+// error
+var (
+	ErrorInvalidRate   = errors.New("ratelimit: rate and burst must be greater than 0")
+	ErrorInvalidWindow = errors.New("ratelimit: limit must be greater than 0 and window at least 1ms")
+)
+
+// ValidateTokenBucket 检查令牌桶的参数，rate为0时无法计算等待时间
+func ValidateTokenBucket(rate float64, burst int) error {
+	if rate <= 0 || burst <= 0 {
+		return ErrorInvalidRate
+	}
+
+	return nil
+}
+
+// ValidateSlidingWindow 检查滑动窗口的参数，窗口按照毫秒计算
+func ValidateSlidingWindow(limit int, size time.Duration) error {
+	if limit <= 0 || size < time.Millisecond {
+		return ErrorInvalidWindow
+	}
+
+	return nil
+}
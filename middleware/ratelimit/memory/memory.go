@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wpajqz/linker/middleware/ratelimit"
+)
+
+// 清理已经恢复到初始状态的key的间隔，清理需要遍历所有key，不能在每个请求中执行
+const cleanupInterval = 10 * time.Second
+
+type (
+	bucket struct {
+		tokens float64
+		last   time.Time
+	}
+
+	tokenBucket struct {
+		mu      sync.Mutex
+		rate    float64
+		burst   float64
+		buckets map[string]*bucket
+		cleaned time.Time
+	}
+
+	window struct {
+		start             time.Time
+		current, previous int
+	}
+
+	slidingWindow struct {
+		mu      sync.Mutex
+		limit   int
+		size    time.Duration
+		windows map[string]*window
+		cleaned time.Time
+	}
+)
+
+// NewTokenBucket 令牌桶算法，每秒生成rate个令牌，最多积累burst个，rate和burst需要大于0
+func NewTokenBucket(rate float64, burst int) (ratelimit.Limiter, error) {
+	if err := ratelimit.ValidateTokenBucket(rate, burst); err != nil {
+		return nil, err
+	}
+
+	return &tokenBucket{rate: rate, burst: float64(burst), buckets: make(map[string]*bucket)}, nil
+}
+
+func (tb *tokenBucket) Allow(key string) (bool, time.Duration, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(tb.cleaned) >= cleanupInterval {
+		tb.cleanup(now)
+	}
+
+	b, ok := tb.buckets[key]
+	if !ok {
+		b = &bucket{tokens: tb.burst, last: now}
+		tb.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * tb.rate
+	if b.tokens > tb.burst {
+		b.tokens = tb.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	return false, time.Duration((1 - b.tokens) / tb.rate * float64(time.Second)), nil
+}
+
+func (tb *tokenBucket) cleanup(now time.Time) {
+	tb.cleaned = now
+	for key, b := range tb.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*tb.rate >= tb.burst {
+			delete(tb.buckets, key)
+		}
+	}
+}
+
+// NewSlidingWindow 滑动窗口算法，任意size时间内最多limit个请求，limit需要大于0，size不能小于1毫秒
+func NewSlidingWindow(limit int, size time.Duration) (ratelimit.Limiter, error) {
+	if err := ratelimit.ValidateSlidingWindow(limit, size); err != nil {
+		return nil, err
+	}
+
+	return &slidingWindow{limit: limit, size: size, windows: make(map[string]*window)}, nil
+}
+
+func (sw *slidingWindow) Allow(key string) (bool, time.Duration, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(sw.cleaned) >= cleanupInterval {
+		sw.cleanup(now)
+	}
+
+	start := now.Truncate(sw.size)
+	w, ok := sw.windows[key]
+	if !ok {
+		w = &window{start: start}
+		sw.windows[key] = w
+	}
+
+	switch {
+	case start.Sub(w.start) == sw.size:
+		w.previous, w.current = w.current, 0
+		w.start = start
+	case start.Sub(w.start) > sw.size:
+		w.previous, w.current = 0, 0
+		w.start = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := float64(sw.size-elapsed) / float64(sw.size)
+	if float64(w.previous)*weight+float64(w.current) >= float64(sw.limit) {
+		return false, sw.size - elapsed, nil
+	}
+
+	w.current++
+
+	return true, 0, nil
+}
+
+func (sw *slidingWindow) cleanup(now time.Time) {
+	sw.cleaned = now
+	start := now.Truncate(sw.size)
+	for key, w := range sw.windows {
+		if start.Sub(w.start) > sw.size {
+			delete(sw.windows, key)
+		}
+	}
+}
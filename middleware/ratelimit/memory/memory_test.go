@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker/middleware/ratelimit"
+)
+
+func TestTokenBucket(t *testing.T) {
+	l, err := NewTokenBucket(10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+
+	ok, retry, _ := l.Allow("a")
+	if ok {
+		t.Fatal("request should be denied after burst")
+	}
+
+	if retry <= 0 || retry > 100*time.Millisecond {
+		t.Errorf("unexpected retry after %s", retry)
+	}
+
+	if ok, _, _ := l.Allow("b"); !ok {
+		t.Error("keys should be limited independently")
+	}
+
+	time.Sleep(retry)
+	if ok, _, _ := l.Allow("a"); !ok {
+		t.Error("request should be allowed after refill")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	l, err := NewSlidingWindow(3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if ok, _, _ := l.Allow("a"); ok {
+			allowed++
+		}
+	}
+
+	if allowed != 3 {
+		t.Errorf("expected 3 allowed requests, got %d", allowed)
+	}
+
+	ok, retry, _ := l.Allow("a")
+	if ok || retry <= 0 || retry > time.Second {
+		t.Errorf("unexpected result %v %s", ok, retry)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	if _, err := NewTokenBucket(0, 1); err != ratelimit.ErrorInvalidRate {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if _, err := NewSlidingWindow(1, 0); err != ratelimit.ErrorInvalidWindow {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	l, err := NewTokenBucket(10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tb := l.(*tokenBucket)
+	_, _, _ = tb.Allow("a")
+	tb.buckets["a"].last = time.Now().Add(-time.Minute)
+
+	// 距离上次清理不到cleanupInterval时不遍历所有key
+	_, _, _ = tb.Allow("b")
+	if _, ok := tb.buckets["a"]; !ok {
+		t.Fatal("bucket should not be cleaned before the interval")
+	}
+
+	tb.cleaned = time.Now().Add(-cleanupInterval)
+	_, _, _ = tb.Allow("b")
+	if _, ok := tb.buckets["a"]; ok || len(tb.buckets) != 1 {
+		t.Fatalf("unexpected buckets: %v", tb.buckets)
+	}
+}
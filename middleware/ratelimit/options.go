@@ -0,0 +1,33 @@
+package ratelimit
+
+type (
+	Options struct {
+		limiter    Limiter
+		key        KeyFunc
+		prefix     string
+		failClosed bool
+	}
+
+	Option func(o *Options)
+)
+
+// 限流的key，默认按照客户端IP
+func Key(fn KeyFunc) Option {
+	return func(o *Options) {
+		o.key = fn
+	}
+}
+
+// key的前缀，多个限流中间件共用一个存储时用于区分
+func Prefix(prefix string) Option {
+	return func(o *Options) {
+		o.prefix = prefix
+	}
+}
+
+// 限流存储出错时拒绝请求，默认放行
+func FailClosed() Option {
+	return func(o *Options) {
+		o.failClosed = true
+	}
+}
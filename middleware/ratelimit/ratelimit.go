@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/wpajqz/linker"
+)
+
+// 请求被拒绝时返回需要等待的秒数的响应属性
+const RetryAfter = "retry-after"
+
+type (
+	// Limiter 限流算法，每次调用消耗key的一次请求配额，拒绝时返回需要等待的时间
+	Limiter interface {
+		Allow(key string) (allowed bool, retryAfter time.Duration, err error)
+	}
+
+	// KeyFunc 从请求中获取限流的key
+	KeyFunc func(ctx linker.Context) string
+
+	// RateLimit 限流中间件，超过限制时返回StatusTooManyRequests
+	RateLimit struct {
+		options Options
+	}
+)
+
+var _ linker.Middleware = new(RateLimit)
+
+func New(limiter Limiter, opts ...Option) *RateLimit {
+	options := Options{
+		limiter: limiter,
+		key:     ByIP(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &RateLimit{options: options}
+}
+
+func (rl *RateLimit) Handle(ctx linker.Context) linker.Context {
+	key := rl.options.key(ctx)
+	if key == "" {
+		return ctx
+	}
+
+	allowed, retryAfter, err := rl.options.limiter.Allow(rl.options.prefix + key)
+	if err != nil {
+		if rl.options.failClosed {
+			ctx.Error(linker.StatusServiceUnavailable, err.Error())
+		}
+
+		return ctx
+	}
+
+	if !allowed {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		ctx.SetResponseProperty(RetryAfter, strconv.Itoa(seconds))
+		ctx.Error(linker.StatusTooManyRequests, linker.StatusText(linker.StatusTooManyRequests))
+	}
+
+	return ctx
+}
+
+// ByIP 按照客户端IP限流
+func ByIP() KeyFunc {
+	return func(ctx linker.Context) string {
+		addr := ctx.RemoteAddr()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			return host
+		}
+
+		return addr
+	}
+}
+
+// ByRemoteAddr 按照客户端地址(IP和端口)限流
+func ByRemoteAddr() KeyFunc {
+	return func(ctx linker.Context) string {
+		return ctx.RemoteAddr()
+	}
+}
+
+// ByNodeID 按照连接限流
+func ByNodeID() KeyFunc {
+	return func(ctx linker.Context) string {
+		return ctx.NodeID()
+	}
+}
+
+// ByRequestProperty 按照请求属性限流，例如用户ID
+func ByRequestProperty(name string) KeyFunc {
+	return func(ctx linker.Context) string {
+		return ctx.GetRequestProperty(name)
+	}
+}
+
+// ByOperator 按照请求的路由限流
+func ByOperator() KeyFunc {
+	return func(ctx linker.Context) string {
+		return strconv.FormatUint(uint64(ctx.Operator()), 10)
+	}
+}
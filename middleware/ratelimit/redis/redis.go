@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis"
+	br "github.com/wpajqz/linker/broker/redis"
+	"github.com/wpajqz/linker/middleware/ratelimit"
+)
+
+const keyPrefix = "linker:ratelimit:"
+
+var ErrorUnexpectedResult = errors.New("ratelimit: unexpected redis result")
+
+// serverTime 使用redis服务器的时间，避免各个节点的时钟偏差，调用TIME之后还需要写入，需要开启命令复制
+const serverTime = `
+redis.replicate_commands()
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+`
+
+var tokenBucketScript = redis.NewScript(serverTime + `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or burst
+local ts = tonumber(data[2]) or now
+tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+local allowed, retry = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HMSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, retry}
+`)
+
+var slidingWindowScript = redis.NewScript(serverTime + `
+local limit = tonumber(ARGV[1])
+local size = tonumber(ARGV[2])
+local current = math.floor(now / size)
+local ck = KEYS[1] .. ':' .. current
+local pk = KEYS[1] .. ':' .. (current - 1)
+local c = tonumber(redis.call('GET', ck) or '0')
+local p = tonumber(redis.call('GET', pk) or '0')
+local elapsed = now - current * size
+if p * (size - elapsed) / size + c >= limit then
+	return {0, size - elapsed}
+end
+redis.call('INCR', ck)
+redis.call('PEXPIRE', ck, size * 2)
+return {1, 0}
+`)
+
+type (
+	tokenBucket struct {
+		client      *redis.Client
+		rate, burst float64
+	}
+
+	slidingWindow struct {
+		client *redis.Client
+		limit  int
+		size   time.Duration
+	}
+)
+
+// NewTokenBucket 基于redis的令牌桶算法，多个节点共享限流状态，rate和burst需要大于0
+func NewTokenBucket(rate float64, burst int, opts ...br.Option) (ratelimit.Limiter, error) {
+	if err := ratelimit.ValidateTokenBucket(rate, burst); err != nil {
+		return nil, err
+	}
+
+	return &tokenBucket{client: newClient(opts...), rate: rate, burst: float64(burst)}, nil
+}
+
+func (tb *tokenBucket) Allow(key string) (bool, time.Duration, error) {
+	return run(tb.client, tokenBucketScript, key, tb.rate, tb.burst)
+}
+
+// NewSlidingWindow 基于redis的滑动窗口算法，多个节点共享限流状态，limit需要大于0，size不能小于1毫秒
+func NewSlidingWindow(limit int, size time.Duration, opts ...br.Option) (ratelimit.Limiter, error) {
+	if err := ratelimit.ValidateSlidingWindow(limit, size); err != nil {
+		return nil, err
+	}
+
+	return &slidingWindow{client: newClient(opts...), limit: limit, size: size}, nil
+}
+
+func (sw *slidingWindow) Allow(key string) (bool, time.Duration, error) {
+	return run(sw.client, slidingWindowScript, key, sw.limit, int64(sw.size/time.Millisecond))
+}
+
+func run(client *redis.Client, script *redis.Script, key string, args ...interface{}) (bool, time.Duration, error) {
+	v, err := script.Run(client, []string{keyPrefix + key}, args...).Result()
+	if err != nil {
+		return false, 0, err
+	}
+
+	result, ok := v.([]interface{})
+	if !ok || len(result) != 2 {
+		return false, 0, ErrorUnexpectedResult
+	}
+
+	allowed, _ := result[0].(int64)
+	retry, _ := result[1].(int64)
+
+	return allowed == 1, time.Duration(retry) * time.Millisecond, nil
+}
+
+// 使用和redis broker相同的连接配置
+func newClient(opts ...br.Option) *redis.Client {
+	options := br.Options{
+		Address: "127.0.0.1:6379",
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return redis.NewClient(&redis.Options{
+		Addr:     options.Address,
+		Password: options.Password,
+		DB:       options.DB,
+	})
+}
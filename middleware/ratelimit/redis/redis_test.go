@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	br "github.com/wpajqz/linker/broker/redis"
+	"github.com/wpajqz/linker/middleware/ratelimit"
+)
+
+func TestTokenBucket(t *testing.T) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	now := time.Now()
+	m.SetTime(now)
+
+	l, err := NewTokenBucket(10, 2, br.Address(m.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if ok, _, err := l.Allow("a"); err != nil || !ok {
+			t.Fatalf("request %d should be allowed: %v", i, err)
+		}
+	}
+
+	ok, retry, err := l.Allow("a")
+	if err != nil || ok || retry != 100*time.Millisecond {
+		t.Fatalf("unexpected result %v %s %v", ok, retry, err)
+	}
+
+	if ok, _, _ := l.Allow("b"); !ok {
+		t.Fatal("keys should be limited independently")
+	}
+
+	// 使用redis服务器的时间计算令牌
+	m.SetTime(now.Add(100 * time.Millisecond))
+	if ok, _, err := l.Allow("a"); err != nil || !ok {
+		t.Fatalf("request should be allowed after refill: %v", err)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	now := time.Unix(1000, 0)
+	m.SetTime(now)
+
+	l, err := NewSlidingWindow(3, time.Second, br.Address(m.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if ok, _, err := l.Allow("a"); err != nil {
+			t.Fatal(err)
+		} else if ok {
+			allowed++
+		}
+	}
+
+	if allowed != 3 {
+		t.Fatalf("expected 3 allowed requests, got %d", allowed)
+	}
+
+	// 下一个窗口过去一半时，上一个窗口的3个请求按照1.5个计入
+	m.SetTime(now.Add(1500 * time.Millisecond))
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d should be allowed in the next window", i)
+		}
+	}
+
+	ok, retry, _ := l.Allow("a")
+	if ok || retry != 500*time.Millisecond {
+		t.Fatalf("unexpected result %v %s", ok, retry)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	if _, err := NewTokenBucket(1, 0); err != ratelimit.ErrorInvalidRate {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if _, err := NewSlidingWindow(1, time.Microsecond); err != ratelimit.ErrorInvalidWindow {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
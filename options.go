@@ -6,7 +6,9 @@ import (
 
 	"github.com/wpajqz/linker/api"
 	"github.com/wpajqz/linker/broker"
+	"github.com/wpajqz/linker/discover"
 	"github.com/wpajqz/linker/plugin"
+	"github.com/wpajqz/linker/presence"
 )
 
 type (
@@ -15,14 +17,33 @@ type (
 		readBufferSize                                               int
 		writeBufferSize                                              int
 		udpPayload                                                   int
+		writeQueueSize                                               int
+		writeBatchSize                                               int
+		writeTimeout                                                 time.Duration
+		flushInterval                                                time.Duration
+		slowConsumer                                                 SlowConsumerPolicy
+		maxConcurrentPerConn                                         int
+		workerPoolSize                                               int
+		overloadPolicy                                               OverloadPolicy
+		inOrder                                                      bool
+		maxBatchSize                                                 int
+		heartbeatInterval                                            time.Duration
+		minHeartbeatInterval, maxHeartbeatInterval                   time.Duration
+		heartbeatTolerance                                           int
 		timeout                                                      time.Duration
 		contentType                                                  string
 		broker                                                       broker.Broker
+		presenceStore                                                presence.Store
 		api                                                          api.API
 		pluginForPacketSender                                        []plugin.PacketPlugin
 		pluginForPacketReceiver                                      []plugin.PacketPlugin
 		errorHandler, constructHandler, destructHandler, pingHandler Handler
+		idleHandler                                                  Handler
+		acceptHandler                                                AcceptFunc
 		httpEndpoint, tcpEndpoint, udpEndpoint                       *Endpoint
+		registry                                                     discover.Registry
+		serviceName, serviceVersion, serviceZone, advertiseHost      string
+		serviceWeight                                                int
 	}
 
 	Endpoint struct {
@@ -52,6 +73,98 @@ func WriteBufferSize(size int) Option {
 	}
 }
 
+// 每个连接写队列的长度
+func WriteQueueSize(size int) Option {
+	return func(o *Options) {
+		o.writeQueueSize = size
+	}
+}
+
+// 合并小包时一次写出的最大字节数，默认64KB
+func WriteBatchSize(size int) Option {
+	return func(o *Options) {
+		o.writeBatchSize = size
+	}
+}
+
+// 每次写出数据的超时时间，超时后断开连接，默认使用Timeout，都没有设置时为10秒
+func WriteTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.writeTimeout = d
+	}
+}
+
+// 合并小包时等待更多数据包的最长时间，为0时只合并已经在队列中的数据包
+func FlushInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.flushInterval = d
+	}
+}
+
+// 写队列已满时的处理策略
+func SlowConsumer(policy SlowConsumerPolicy) Option {
+	return func(o *Options) {
+		o.slowConsumer = policy
+	}
+}
+
+// 单个连接同时处理的最大请求数，为0时不限制，udp没有连接，只受WorkerPoolSize限制
+func MaxConcurrentRequestsPerConn(n int) Option {
+	return func(o *Options) {
+		o.maxConcurrentPerConn = n
+	}
+}
+
+// 全局同时处理的最大请求数，为0时不限制
+func WorkerPoolSize(n int) Option {
+	return func(o *Options) {
+		o.workerPoolSize = n
+	}
+}
+
+// 超过并发限制时排队等待还是直接拒绝
+func Overload(policy OverloadPolicy) Option {
+	return func(o *Options) {
+		o.overloadPolicy = policy
+	}
+}
+
+// 同一连接上的请求严格按照到达顺序逐个处理，处理期间不读取新的数据包，因此请求不会被取消帧取消，对udp不生效
+func InOrder() Option {
+	return func(o *Options) {
+		o.inOrder = true
+	}
+}
+
+// 批量请求中允许携带的最大子请求数，为0时不限制
+func MaxBatchSize(n int) Option {
+	return func(o *Options) {
+		o.maxBatchSize = n
+	}
+}
+
+// 默认的心跳间隔，为0时不检测空闲连接
+func HeartbeatInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.heartbeatInterval = d
+	}
+}
+
+// 允许客户端协商的心跳间隔范围，为0时不限制
+func HeartbeatRange(min, max time.Duration) Option {
+	return func(o *Options) {
+		o.minHeartbeatInterval = min
+		o.maxHeartbeatInterval = max
+	}
+}
+
+// 连续多少个心跳间隔没有收到数据时认为连接空闲
+func HeartbeatTolerance(n int) Option {
+	return func(o *Options) {
+		o.heartbeatTolerance = n
+	}
+}
+
 func UDPPayload(size int) Option {
 	return func(o *Options) {
 		o.udpPayload = size
@@ -82,6 +195,13 @@ func Broker(broker broker.Broker) Option {
 	}
 }
 
+// 在线状态的存储方式，默认保存在内存中
+func PresenceStore(store presence.Store) Option {
+	return func(o *Options) {
+		o.presenceStore = store
+	}
+}
+
 func PluginForPacketSender(plugins ...plugin.PacketPlugin) Option {
 	return func(o *Options) {
 		o.pluginForPacketSender = append(o.pluginForPacketSender, plugins...)
@@ -100,18 +220,34 @@ func WithOnError(handler Handler) Option {
 	}
 }
 
+// 长连接断开时执行，udp没有连接，不会执行
 func WithOnClose(handler Handler) Option {
 	return func(o *Options) {
 		o.destructHandler = handler
 	}
 }
 
+// 长连接建立时执行，udp没有连接，不会执行
 func WithOnOpen(handler Handler) Option {
 	return func(o *Options) {
 		o.constructHandler = handler
 	}
 }
 
+// 连接建立后读取第一个数据包进行准入判断，拒绝时不会执行任何handler
+func WithOnAccept(fn AcceptFunc) Option {
+	return func(o *Options) {
+		o.acceptHandler = fn
+	}
+}
+
+// 连接空闲被断开之前执行
+func WithOnIdle(handler Handler) Option {
+	return func(o *Options) {
+		o.idleHandler = handler
+	}
+}
+
 func WithOnPing(handler Handler) Option {
 	return func(o *Options) {
 		o.pingHandler = handler
@@ -135,3 +271,43 @@ func WithUDPEndpoint(e Endpoint) Option {
 		o.udpEndpoint = &e
 	}
 }
+
+// 监听成功以后把tcp、udp、websocket地址注册到服务发现中，关闭服务时先注销再断开连接
+func Registry(reg discover.Registry) Option {
+	return func(o *Options) {
+		o.registry = reg
+	}
+}
+
+// 注册到服务发现中的服务名称，默认linker
+func ServiceName(name string) Option {
+	return func(o *Options) {
+		o.serviceName = name
+	}
+}
+
+func ServiceVersion(version string) Option {
+	return func(o *Options) {
+		o.serviceVersion = version
+	}
+}
+
+// 服务实例的权重，客户端可以根据权重进行负载均衡
+func ServiceWeight(weight int) Option {
+	return func(o *Options) {
+		o.serviceWeight = weight
+	}
+}
+
+func ServiceZone(zone string) Option {
+	return func(o *Options) {
+		o.serviceZone = zone
+	}
+}
+
+// 注册到服务发现中的主机地址，监听地址没有指定ip时使用
+func AdvertiseHost(host string) Option {
+	return func(o *Options) {
+		o.advertiseHost = host
+	}
+}
@@ -2,6 +2,7 @@ package linker
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/wpajqz/linker/plugin"
 	"github.com/wpajqz/linker/utils/convert"
@@ -53,3 +54,45 @@ func (p Packet) Bytes() (buf []byte) {
 
 	return buf
 }
+
+// ReadPacket 从数据流中读取一个完整的数据包
+func ReadPacket(r io.Reader, plugins []plugin.PacketPlugin) (Packet, error) {
+	var (
+		bType         = make([]byte, 4)
+		bSequence     = make([]byte, 8)
+		bHeaderLength = make([]byte, 4)
+		bBodyLength   = make([]byte, 4)
+	)
+
+	if _, err := io.ReadFull(r, bType); err != nil {
+		return Packet{}, err
+	}
+
+	if _, err := io.ReadFull(r, bSequence); err != nil {
+		return Packet{}, err
+	}
+
+	if _, err := io.ReadFull(r, bHeaderLength); err != nil {
+		return Packet{}, err
+	}
+
+	if _, err := io.ReadFull(r, bBodyLength); err != nil {
+		return Packet{}, err
+	}
+
+	sequence := convert.BytesToInt64(bSequence)
+	headerLength := convert.BytesToUint32(bHeaderLength)
+	bodyLength := convert.BytesToUint32(bBodyLength)
+
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return Packet{}, err
+	}
+
+	body := make([]byte, bodyLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return Packet{}, err
+	}
+
+	return NewPacket(convert.BytesToUint32(bType), sequence, header, body, plugins)
+}
@@ -0,0 +1,60 @@
+package linker
+
+import (
+	"github.com/wpajqz/linker/broker"
+	"github.com/wpajqz/linker/codec"
+	"github.com/wpajqz/linker/presence"
+)
+
+// 在线状态事件发布的topic
+const TopicPresence = internalTopicPrefix + "presence"
+
+// Presence 集群范围内的在线状态，以连接绑定的身份标识为key
+type Presence struct {
+	store  presence.Store
+	broker broker.Broker
+}
+
+// IsOnline 身份标识在集群中是否至少有一个连接
+func (p *Presence) IsOnline(id string) (bool, error) {
+	return p.store.IsOnline(id)
+}
+
+// List 集群中所有在线的身份标识
+func (p *Presence) List() ([]string, error) {
+	return p.store.List()
+}
+
+// join 记录连接，身份标识的第一个连接上线时发布上线事件
+func (p *Presence) join(id, nodeID string) error {
+	first, err := p.store.Add(id, nodeID)
+	if err != nil || !first {
+		return err
+	}
+
+	return p.publish(presence.Event{Type: presence.EventJoin, ID: id, NodeID: nodeID, Online: true})
+}
+
+// leave 移除连接，身份标识的最后一个连接断开时发布下线事件
+func (p *Presence) leave(id, nodeID string) error {
+	last, err := p.store.Remove(id, nodeID)
+	if err != nil || !last {
+		return err
+	}
+
+	return p.publish(presence.Event{Type: presence.EventLeave, ID: id, NodeID: nodeID, Online: false})
+}
+
+func (p *Presence) publish(event presence.Event) error {
+	coder, err := codec.NewCoder(codec.JSON)
+	if err != nil {
+		return err
+	}
+
+	data, err := coder.Encoder(event)
+	if err != nil {
+		return err
+	}
+
+	return p.broker.Publish(TopicPresence, data)
+}
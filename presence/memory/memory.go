@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/wpajqz/linker/presence"
+)
+
+type memoryStore struct {
+	mu    sync.RWMutex
+	nodes map[string]map[string]struct{}
+}
+
+func (ms *memoryStore) Add(id, nodeID string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	m, ok := ms.nodes[id]
+	if !ok {
+		m = make(map[string]struct{})
+		ms.nodes[id] = m
+	}
+
+	m[nodeID] = struct{}{}
+
+	return !ok, nil
+}
+
+func (ms *memoryStore) Remove(id, nodeID string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	m, ok := ms.nodes[id]
+	if !ok {
+		return false, nil
+	}
+
+	delete(m, nodeID)
+	if len(m) == 0 {
+		delete(ms.nodes, id)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (ms *memoryStore) IsOnline(id string) (bool, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	_, ok := ms.nodes[id]
+
+	return ok, nil
+}
+
+func (ms *memoryStore) List() ([]string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	list := make([]string, 0, len(ms.nodes))
+	for id := range ms.nodes {
+		list = append(list, id)
+	}
+
+	return list, nil
+}
+
+func NewStore() presence.Store {
+	return &memoryStore{nodes: make(map[string]map[string]struct{})}
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/wpajqz/linker/presence"
+	"github.com/wpajqz/linker/presence/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) presence.Store {
+		return NewStore()
+	})
+}
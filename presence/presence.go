@@ -0,0 +1,28 @@
+package presence
+
+// 上线/下线事件类型
+const (
+	EventJoin  = "join"
+	EventLeave = "leave"
+)
+
+type (
+	// Store 在线状态存储，记录每个身份标识在集群中的所有连接
+	Store interface {
+		// Add 记录身份标识的一个连接，first表示这是该身份标识的第一个连接
+		Add(id, nodeID string) (first bool, err error)
+		// Remove 移除身份标识的一个连接，last表示移除后该身份标识已经没有连接
+		Remove(id, nodeID string) (last bool, err error)
+		IsOnline(id string) (bool, error)
+		List() ([]string, error)
+	}
+
+	// Event 身份标识的第一个连接上线或者最后一个连接断开时发布的事件，NodeID为触发事件的连接，
+	// Online表示事件发生后该身份标识是否在线
+	Event struct {
+		Type   string `json:"type"`
+		ID     string `json:"id"`
+		NodeID string `json:"node_id"`
+		Online bool   `json:"online"`
+	}
+)
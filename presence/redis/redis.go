@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	uuid "github.com/satori/go.uuid"
+	br "github.com/wpajqz/linker/broker/redis"
+	"github.com/wpajqz/linker/presence"
+)
+
+const (
+	keyOnline       = "linker:presence:online"
+	keyUserPrefix   = "linker:presence:user:"
+	keyServerPrefix = "linker:presence:server:"
+)
+
+// 服务节点的存活时间，节点异常退出以后，超过该时间其上的连接不再视为在线
+const serverTTL = 30 * time.Second
+
+// prune 移除所在服务节点已经过期的连接，返回剩余的连接数
+const prune = `
+local function prune(key, prefix)
+	local fields = redis.call('HGETALL', key)
+	for i = 1, #fields, 2 do
+		if redis.call('EXISTS', prefix .. fields[i + 1]) == 0 then
+			redis.call('HDEL', key, fields[i])
+		end
+	end
+
+	return redis.call('HLEN', key)
+end
+`
+
+var (
+	// KEYS: 用户连接, 在线列表, 服务节点 ARGV: 身份标识, 连接, 服务节点, 存活时间, 服务节点前缀
+	addScript = redis.NewScript(prune + `
+redis.call('SET', KEYS[3], 1, 'PX', ARGV[4])
+local n = prune(KEYS[1], ARGV[5])
+redis.call('HSET', KEYS[1], ARGV[2], ARGV[3])
+redis.call('SADD', KEYS[2], ARGV[1])
+if n == 0 then
+	return 1
+end
+return 0
+`)
+
+	// KEYS: 用户连接, 在线列表 ARGV: 身份标识, 连接, 服务节点前缀
+	removeScript = redis.NewScript(prune + `
+local removed = redis.call('HDEL', KEYS[1], ARGV[2])
+if prune(KEYS[1], ARGV[3]) > 0 then
+	return 0
+end
+redis.call('SREM', KEYS[2], ARGV[1])
+return removed
+`)
+
+	// KEYS: 用户连接, 在线列表 ARGV: 身份标识, 服务节点前缀
+	isOnlineScript = redis.NewScript(prune + `
+local n = prune(KEYS[1], ARGV[2])
+if n == 0 then
+	redis.call('SREM', KEYS[2], ARGV[1])
+end
+return n
+`)
+
+	// KEYS: 在线列表 ARGV: 用户连接前缀, 服务节点前缀
+	listScript = redis.NewScript(prune + `
+local online = {}
+for _, id in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+	if prune(ARGV[1] .. id, ARGV[2]) > 0 then
+		table.insert(online, id)
+	else
+		redis.call('SREM', KEYS[1], id)
+	end
+end
+return online
+`)
+)
+
+// redisStore 每个连接记录所在的服务节点，服务节点定时续期，节点异常退出以后其上的连接自动下线
+type redisStore struct {
+	client   *redis.Client
+	serverID string
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (rs *redisStore) Add(id, nodeID string) (bool, error) {
+	keys := []string{keyUserPrefix + id, keyOnline, keyServerPrefix + rs.serverID}
+	n, err := addScript.Run(rs.client, keys, id, nodeID, rs.serverID, int64(serverTTL/time.Millisecond), keyServerPrefix).Int64()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (rs *redisStore) Remove(id, nodeID string) (bool, error) {
+	n, err := removeScript.Run(rs.client, []string{keyUserPrefix + id, keyOnline}, id, nodeID, keyServerPrefix).Int64()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (rs *redisStore) IsOnline(id string) (bool, error) {
+	n, err := isOnlineScript.Run(rs.client, []string{keyUserPrefix + id, keyOnline}, id, keyServerPrefix).Int64()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (rs *redisStore) List() ([]string, error) {
+	v, err := listScript.Run(rs.client, []string{keyOnline}, keyUserPrefix, keyServerPrefix).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values, _ := v.([]interface{})
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			list = append(list, id)
+		}
+	}
+
+	return list, nil
+}
+
+// Close 停止服务节点续期，服务关闭时调用
+func (rs *redisStore) Close() error {
+	rs.once.Do(func() {
+		close(rs.done)
+	})
+
+	return nil
+}
+
+// heartbeat 定时为服务节点续期
+func (rs *redisStore) heartbeat() {
+	ticker := time.NewTicker(serverTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = rs.client.Set(keyServerPrefix+rs.serverID, 1, serverTTL).Err()
+		case <-rs.done:
+			return
+		}
+	}
+}
+
+// NewStore 使用和redis broker相同的连接配置
+func NewStore(opts ...br.Option) presence.Store {
+	options := br.Options{
+		Address: "127.0.0.1:6379",
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	rc := redis.NewClient(&redis.Options{
+		Addr:     options.Address,
+		Password: options.Password,
+		DB:       options.DB,
+	})
+
+	rs := &redisStore{client: rc, serverID: uuid.NewV4().String(), done: make(chan struct{})}
+	go rs.heartbeat()
+
+	return rs
+}
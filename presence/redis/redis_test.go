@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"io"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	br "github.com/wpajqz/linker/broker/redis"
+	"github.com/wpajqz/linker/presence"
+	"github.com/wpajqz/linker/presence/storetest"
+)
+
+func newStore(t *testing.T, mr *miniredis.Miniredis) presence.Store {
+	s := NewStore(br.Address(mr.Addr()))
+	t.Cleanup(func() { _ = s.(io.Closer).Close() })
+
+	return s
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) presence.Store {
+		return newStore(t, miniredis.RunT(t))
+	})
+}
+
+func TestServerExpired(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	crashed, alive := newStore(t, mr), newStore(t, mr)
+
+	if _, err := crashed.Add("u1", "c1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := alive.Add("u2", "c2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 节点异常退出以后不再续期，超过存活时间以后其上的连接不再在线
+	_ = crashed.(io.Closer).Close()
+	mr.FastForward(serverTTL)
+
+	// 正常的节点在此期间已经续期
+	if err := mr.Set(keyServerPrefix+alive.(*redisStore).serverID, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := alive.IsOnline("u1"); err != nil || ok {
+		t.Fatalf("unexpected online: %v %v", ok, err)
+	}
+
+	list, err := alive.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 1 || list[0] != "u2" {
+		t.Fatalf("unexpected list: %v", list)
+	}
+
+	// 用户在其他节点重新上线时视为第一个连接
+	if first, err := alive.Add("u1", "c3"); err != nil || !first {
+		t.Fatalf("unexpected first: %v %v", first, err)
+	}
+}
@@ -0,0 +1,181 @@
+// Package storetest 提供presence.Store实现需要通过的一致性测试
+package storetest
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/wpajqz/linker/presence"
+)
+
+// Run 运行一致性测试，newStore每次需要返回一个空的存储
+func Run(t *testing.T, newStore func(t *testing.T) presence.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s presence.Store)
+	}{
+		{"AddRemove", testAddRemove},
+		{"List", testList},
+		{"RemoveUnknown", testRemoveUnknown},
+		{"Concurrent", testConcurrent},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func add(t *testing.T, s presence.Store, id, nodeID string, want bool) {
+	t.Helper()
+
+	first, err := s.Add(id, nodeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != want {
+		t.Fatalf("Add(%s, %s) first = %v, want %v", id, nodeID, first, want)
+	}
+}
+
+func remove(t *testing.T, s presence.Store, id, nodeID string, want bool) {
+	t.Helper()
+
+	last, err := s.Remove(id, nodeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last != want {
+		t.Fatalf("Remove(%s, %s) last = %v, want %v", id, nodeID, last, want)
+	}
+}
+
+func online(t *testing.T, s presence.Store, id string, want bool) {
+	t.Helper()
+
+	ok, err := s.IsOnline(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok != want {
+		t.Fatalf("IsOnline(%s) = %v, want %v", id, ok, want)
+	}
+}
+
+func testAddRemove(t *testing.T, s presence.Store) {
+	online(t, s, "u1", false)
+
+	add(t, s, "u1", "c1", true)
+	add(t, s, "u1", "c2", false)
+	online(t, s, "u1", true)
+
+	remove(t, s, "u1", "c1", false)
+	online(t, s, "u1", true)
+
+	remove(t, s, "u1", "c2", true)
+	online(t, s, "u1", false)
+
+	// 全部下线以后重新上线
+	add(t, s, "u1", "c3", true)
+}
+
+func testList(t *testing.T, s presence.Store) {
+	add(t, s, "u1", "c1", true)
+	add(t, s, "u2", "c2", true)
+	add(t, s, "u2", "c3", false)
+	remove(t, s, "u2", "c2", false)
+
+	list, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(list)
+	if len(list) != 2 || list[0] != "u1" || list[1] != "u2" {
+		t.Fatalf("unexpected list: %v", list)
+	}
+
+	remove(t, s, "u1", "c1", true)
+
+	list, err = s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 1 || list[0] != "u2" {
+		t.Fatalf("unexpected list: %v", list)
+	}
+}
+
+func testRemoveUnknown(t *testing.T, s presence.Store) {
+	remove(t, s, "u1", "c1", false)
+
+	add(t, s, "u1", "c1", true)
+	remove(t, s, "u1", "c2", false)
+	online(t, s, "u1", true)
+}
+
+// testConcurrent 同一个身份标识的连接并发上下线，只有一个连接是第一个，也只有一个连接是最后一个
+func testConcurrent(t *testing.T, s presence.Store) {
+	const n = 20
+
+	var (
+		wg            sync.WaitGroup
+		mu            sync.Mutex
+		firsts, lasts int
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(nodeID string) {
+			defer wg.Done()
+
+			first, err := s.Add("u1", nodeID)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mu.Lock()
+			if first {
+				firsts++
+			}
+			mu.Unlock()
+		}(string(rune('a' + i)))
+	}
+
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(nodeID string) {
+			defer wg.Done()
+
+			last, err := s.Remove("u1", nodeID)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mu.Lock()
+			if last {
+				lasts++
+			}
+			mu.Unlock()
+		}(string(rune('a' + i)))
+	}
+
+	wg.Wait()
+
+	if firsts != 1 || lasts != 1 {
+		t.Fatalf("unexpected firsts: %d, lasts: %d", firsts, lasts)
+	}
+
+	online(t, s, "u1", false)
+}
@@ -0,0 +1,69 @@
+package linker_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/broker/memory"
+	"github.com/wpajqz/linker/presence"
+)
+
+func TestPresenceEvents(t *testing.T) {
+	b := memory.NewBroker()
+
+	events := make(chan presence.Event, 10)
+	err := b.Subscribe("observer", linker.TopicPresence, func(data []byte) {
+		var event presence.Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			t.Error(err)
+		}
+
+		events <- event
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, address := newUserServer(t, linker.Broker(b))
+
+	// 同一个身份标识的多个连接只发布一次上线事件
+	c1 := loginUser(t, address, "u1", nil)
+	c2 := loginUser(t, address, "u1", nil)
+
+	expectEvent(t, events, presence.EventJoin, true)
+
+	if ok, err := s.Presence().IsOnline("u1"); err != nil || !ok {
+		t.Fatalf("unexpected online: %v %v", ok, err)
+	}
+
+	// 最后一个连接断开时才发布下线事件
+	_ = c1.Close()
+	_ = c2.Close()
+
+	expectEvent(t, events, presence.EventLeave, false)
+
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event: %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if ok, err := s.Presence().IsOnline("u1"); err != nil || ok {
+		t.Fatalf("unexpected online: %v %v", ok, err)
+	}
+}
+
+func expectEvent(t *testing.T, events <-chan presence.Event, typ string, online bool) {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		if event.Type != typ || event.ID != "u1" || event.Online != online {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("%s event not published", typ)
+	}
+}
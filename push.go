@@ -0,0 +1,58 @@
+package linker
+
+import (
+	"strings"
+
+	"github.com/wpajqz/linker/codec"
+)
+
+// 内部topic的前缀，只能通过Bind、Join等方法订阅，客户端不能直接监听
+const internalTopicPrefix = "/linker/"
+
+// internalTopic topic是否属于内部保留的topic
+func internalTopic(topic string) bool {
+	return strings.HasPrefix(topic, internalTopicPrefix)
+}
+
+// 通过broker在节点之间转发的推送消息
+type pushMessage struct {
+	Operator string `json:"operator"`
+	Body     []byte `json:"body"`
+	Exclude  string `json:"exclude,omitempty"`
+}
+
+// publishPush 将推送消息发布到broker，所有订阅了topic的连接都会收到
+func (s *Server) publishPush(topic string, msg pushMessage) error {
+	coder, err := codec.NewCoder(codec.JSON)
+	if err != nil {
+		return err
+	}
+
+	data, err := coder.Encoder(msg)
+	if err != nil {
+		return err
+	}
+
+	return s.options.broker.Publish(topic, data)
+}
+
+// subscribePush 连接订阅topic，收到推送消息后写给客户端
+func (c *Connection) subscribePush(topic string) error {
+	return c.options.broker.Subscribe(c.nodeID, topic, func(data []byte) {
+		coder, err := codec.NewCoder(codec.JSON)
+		if err != nil {
+			return
+		}
+
+		var msg pushMessage
+		if err := coder.Decoder(data, &msg); err != nil {
+			return
+		}
+
+		if msg.Exclude == c.nodeID {
+			return
+		}
+
+		_, _ = c.Write(msg.Operator, msg.Body)
+	})
+}
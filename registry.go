@@ -0,0 +1,103 @@
+package linker
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/wpajqz/linker/discover"
+)
+
+// 注册到服务发现中的实例信息，权重和可用区使用discover中定义的key，discover.SelectInstance可以直接使用
+const (
+	MetadataVersion  = "version"
+	MetadataWeight   = discover.MetadataWeight
+	MetadataZone     = discover.MetadataZone
+	MetadataProtocol = "protocol"
+	MetadataWSRoute  = "ws_route"
+)
+
+// register 监听成功以后把地址注册到服务发现中，关闭服务时注销
+func (s *Server) register(protocol string, addr net.Addr, metadata map[string]string) error {
+	if s.options.registry == nil {
+		return nil
+	}
+
+	m := map[string]string{
+		MetadataProtocol: protocol,
+		MetadataWeight:   strconv.Itoa(s.options.serviceWeight),
+	}
+
+	if s.options.serviceVersion != "" {
+		m[MetadataVersion] = s.options.serviceVersion
+	}
+
+	if s.options.serviceZone != "" {
+		m[MetadataZone] = s.options.serviceZone
+	}
+
+	for k, v := range metadata {
+		m[k] = v
+	}
+
+	instance := discover.Instance{
+		ID:       s.id + "-" + protocol,
+		Service:  s.options.serviceName,
+		Address:  s.advertise(addr),
+		Metadata: m,
+	}
+
+	if err := s.options.registry.Register(context.Background(), instance); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.instances = append(s.instances, instance)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// deregister 注销所有已经注册的地址
+func (s *Server) deregister(ctx context.Context) error {
+	s.mu.Lock()
+	instances := s.instances
+	s.instances = nil
+	s.mu.Unlock()
+
+	var err error
+	for _, instance := range instances {
+		if e := s.options.registry.Deregister(ctx, instance); e != nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// advertise 监听地址没有指定ip时，使用指定的地址或者本机第一个非回环的ipv4地址
+func (s *Server) advertise(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	if s.options.advertiseHost != "" {
+		return net.JoinHostPort(s.options.advertiseHost, port)
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+		return addr.String()
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err == nil {
+		for _, a := range addrs {
+			if ipn, ok := a.(*net.IPNet); ok && !ipn.IP.IsLoopback() && ipn.IP.To4() != nil {
+				return net.JoinHostPort(ipn.IP.String(), port)
+			}
+		}
+	}
+
+	return net.JoinHostPort("127.0.0.1", port)
+}
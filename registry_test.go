@@ -0,0 +1,143 @@
+package linker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/client/export"
+	"github.com/wpajqz/linker/codec"
+	"github.com/wpajqz/linker/discover"
+	"github.com/wpajqz/linker/discover/memory"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+// orderedRegistry 记录注销时服务端剩余的连接数
+type orderedRegistry struct {
+	*memory.Registry
+	server      *linker.Server
+	connections int
+}
+
+func (r *orderedRegistry) Deregister(ctx context.Context, instance discover.Instance) error {
+	r.connections = r.server.Connections().Count()
+	return r.Registry.Deregister(ctx, instance)
+}
+
+func TestRegistry(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	address, httpAddress := servertest.Address(t), servertest.Address(t)
+
+	reg := &orderedRegistry{Registry: memory.NewRegistry()}
+	s := linker.NewServer(
+		linker.WithTCPEndpoint(linker.Endpoint{Address: address}),
+		linker.WithUDPEndpoint(linker.Endpoint{Address: address}),
+		linker.WithHTTPEndpoint(linker.Endpoint{Address: httpAddress, WSRoute: "/ws", Handler: gin.New()}),
+		linker.Registry(reg),
+		linker.ServiceName("echo"),
+		linker.ServiceVersion("v1"),
+		linker.ServiceZone("zone-a"),
+		linker.ServiceWeight(5),
+		// 响应在写队列中停留一段时间，关闭服务时需要先写出队列中的数据
+		linker.FlushInterval(300*time.Millisecond),
+	)
+	reg.server = s
+
+	router := linker.NewRouter()
+	router.Route("/slow", linker.HandlerFunc(func(ctx linker.Context) {
+		time.Sleep(200 * time.Millisecond)
+		ctx.Success("done")
+	}))
+	s.BindRouter(router)
+
+	servertest.Run(t, s, address)
+
+	// 每个协议监听成功以后分别注册
+	var list []discover.Instance
+	for deadline := time.Now().Add(3 * time.Second); len(list) < 3 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var err error
+		if list, err = reg.List(context.Background(), "echo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	protocols := make(map[string]string)
+	for _, instance := range list {
+		if instance.Metadata[linker.MetadataVersion] != "v1" || instance.Metadata[linker.MetadataZone] != "zone-a" || instance.Metadata[linker.MetadataWeight] != "5" {
+			t.Fatalf("unexpected metadata: %v", instance.Metadata)
+		}
+
+		protocols[instance.Metadata[linker.MetadataProtocol]] = instance.Address
+	}
+
+	if len(protocols) != 3 || protocols[linker.NetworkTCP] != address || protocols[linker.NetworkWebSocket] != httpAddress {
+		t.Fatalf("unexpected instances: %v", list)
+	}
+
+	// 注册的权重和可用区可以直接用于筛选实例
+	if instance, err := discover.SelectInstance(list, discover.InZone("zone-a")); err != nil || instance.Info().Weight != 5 {
+		t.Fatalf("unexpected selection: %+v %v", instance, err)
+	}
+
+	c, err := export.NewClient(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.SetContentType(codec.JSON)
+
+	// 关闭服务之前发出的请求可以正常处理完成
+	result := make(chan string, 1)
+	err = c.AsyncSend("/slow", nil, &requestCallback{success: func(header, body []byte) { result <- string(body) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case body := <-result:
+		if body != `"done"` {
+			t.Fatalf("unexpected response: %s", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("in-flight request not finished before shutdown")
+	}
+
+	if reg.connections != 1 {
+		t.Fatalf("deregister should happen before draining connections, connections: %d", reg.connections)
+	}
+
+	if list, _ := reg.List(context.Background(), "echo"); len(list) != 0 {
+		t.Fatalf("instances not deregistered: %v", list)
+	}
+
+	if s.Connections().Count() != 0 {
+		t.Fatalf("connections not closed: %d", s.Connections().Count())
+	}
+}
+
+type requestCallback struct {
+	success func(header, body []byte)
+}
+
+func (rc *requestCallback) OnSuccess(header, body []byte) {
+	rc.success(header, body)
+}
+
+func (rc *requestCallback) OnError(status int, message string) {}
+
+func (rc *requestCallback) OnStart() {}
+
+func (rc *requestCallback) OnEnd() {}
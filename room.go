@@ -0,0 +1,110 @@
+package linker
+
+const topicRoomPrefix = internalTopicPrefix + "room/"
+
+func roomTopic(room string) string {
+	return topicRoomPrefix + room
+}
+
+// Broadcast 向房间内的所有连接推送数据，excludeNodeID不为空时跳过该连接，通常用于排除消息发送者
+func (s *Server) Broadcast(room, operator string, body []byte, excludeNodeID string) error {
+	return s.publishPush(roomTopic(room), pushMessage{Operator: operator, Body: body, Exclude: excludeNodeID})
+}
+
+// LocalMembers 获取当前节点上加入了房间的连接，不包含其他节点上的连接
+func (s *Server) LocalMembers(room string) []*Connection {
+	return s.connections.Members(room)
+}
+
+// Join 当前连接加入房间，连接断开时自动离开
+func (dc *common) Join(room string) error {
+	if dc.connection == nil {
+		return ErrorConnectionNotFound
+	}
+
+	c := dc.connection
+	if err := c.connections.join(c.nodeID, room); err != nil {
+		return err
+	}
+
+	return c.subscribePush(roomTopic(room))
+}
+
+// Leave 当前连接离开房间
+func (dc *common) Leave(room string) error {
+	if dc.connection == nil {
+		return ErrorConnectionNotFound
+	}
+
+	c := dc.connection
+	c.connections.leave(c.nodeID, room)
+
+	return dc.options.broker.UnSubscribe(c.nodeID, roomTopic(room))
+}
+
+func (cs *Connections) join(nodeID, room string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	c, ok := cs.nodes[nodeID]
+	if !ok {
+		return ErrorConnectionNotFound
+	}
+
+	if cs.rooms[room] == nil {
+		cs.rooms[room] = make(map[string]*Connection)
+	}
+
+	cs.rooms[room][nodeID] = c
+	c.rooms[room] = struct{}{}
+
+	return nil
+}
+
+func (cs *Connections) leave(nodeID, room string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if c, ok := cs.nodes[nodeID]; ok {
+		cs.leaveRoom(c, room)
+	}
+}
+
+func (cs *Connections) leaveRoom(c *Connection, room string) {
+	delete(c.rooms, room)
+
+	if m, ok := cs.rooms[room]; ok {
+		delete(m, c.nodeID)
+		if len(m) == 0 {
+			delete(cs.rooms, room)
+		}
+	}
+}
+
+// Members 获取加入了房间的连接
+func (cs *Connections) Members(room string) []*Connection {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	var list []*Connection
+	for _, c := range cs.rooms[room] {
+		list = append(list, c)
+	}
+
+	return list
+}
+
+// Rooms 获取连接加入的所有房间
+func (cs *Connections) Rooms(nodeID string) []string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	var list []string
+	if c, ok := cs.nodes[nodeID]; ok {
+		for room := range c.rooms {
+			list = append(list, room)
+		}
+	}
+
+	return list
+}
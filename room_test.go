@@ -0,0 +1,151 @@
+package linker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/broker/memory"
+	"github.com/wpajqz/linker/client/export"
+	"github.com/wpajqz/linker/codec"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+func newRoomServer(t *testing.T, opts ...linker.Option) (*linker.Server, string) {
+	router := linker.NewRouter()
+	router.Route("/join", linker.HandlerFunc(func(ctx linker.Context) {
+		var room string
+		if err := ctx.ParseParam(&room); err != nil {
+			ctx.Error(linker.StatusBadRequest, err.Error())
+		}
+
+		if err := ctx.Join(room); err != nil {
+			ctx.Error(linker.StatusInternalServerError, err.Error())
+		}
+
+		ctx.Success(nil)
+	}))
+	router.Route("/leave", linker.HandlerFunc(func(ctx linker.Context) {
+		var room string
+		if err := ctx.ParseParam(&room); err != nil {
+			ctx.Error(linker.StatusBadRequest, err.Error())
+		}
+
+		if err := ctx.Leave(room); err != nil {
+			ctx.Error(linker.StatusInternalServerError, err.Error())
+		}
+
+		ctx.Success(nil)
+	}))
+
+	return servertest.Start(t, router, opts...)
+}
+
+func joinRoom(t *testing.T, address, name, room string, received chan<- string) *export.Client {
+	c, err := export.NewClient(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = c.Close() })
+
+	c.SetContentType(codec.JSON)
+
+	err = c.AddMessageListener("/notify", export.HandlerFunc(func(header, body []byte) {
+		received <- name + ":" + string(body)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SyncSend("/join", room, &statusCallback{t: t}); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// expectMessages 等待收到全部期望的消息，并且没有多余的消息
+func expectMessages(t *testing.T, received <-chan string, expected ...string) {
+	t.Helper()
+
+	got := make(map[string]bool)
+	for range expected {
+		select {
+		case msg := <-received:
+			got[msg] = true
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timeout waiting for messages, received %v", got)
+		}
+	}
+
+	for _, msg := range expected {
+		if !got[msg] {
+			t.Fatalf("unexpected messages %v", got)
+		}
+	}
+
+	select {
+	case msg := <-received:
+		t.Fatalf("unexpected message %s", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestRoom(t *testing.T) {
+	b := memory.NewBroker()
+
+	s1, a1 := newRoomServer(t, linker.Broker(b))
+	s2, a2 := newRoomServer(t, linker.Broker(b))
+
+	received := make(chan string, 8)
+	c1 := joinRoom(t, a1, "c1", "r", received)
+	joinRoom(t, a2, "c2", "r", received)
+	c3 := joinRoom(t, a1, "c3", "r", received)
+	joinRoom(t, a1, "c4", "other", received)
+
+	if len(s1.LocalMembers("r")) != 2 || len(s2.LocalMembers("r")) != 1 {
+		t.Fatalf("unexpected members: %d, %d", len(s1.LocalMembers("r")), len(s2.LocalMembers("r")))
+	}
+
+	// 房间内所有节点上的连接都会收到广播
+	if err := s2.Broadcast("r", "/notify", []byte("hello"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	expectMessages(t, received, "c1:hello", "c2:hello", "c3:hello")
+
+	if err := c3.SyncSend("/leave", "r", &statusCallback{t: t}); err != nil {
+		t.Fatal(err)
+	}
+
+	members := s1.LocalMembers("r")
+	if len(members) != 1 {
+		t.Fatalf("unexpected members: %d", len(members))
+	}
+
+	// 排除消息发送者
+	if err := s1.Broadcast("r", "/notify", []byte("again"), members[0].NodeID()); err != nil {
+		t.Fatal(err)
+	}
+
+	expectMessages(t, received, "c2:again")
+
+	// 连接断开以后自动离开房间
+	_ = c1.Close()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for len(s1.LocalMembers("r")) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("closed connection still in room")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := s1.Broadcast("r", "/notify", []byte("bye"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	expectMessages(t, received, "c2:bye")
+}
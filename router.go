@@ -2,6 +2,7 @@ package linker
 
 import (
 	"hash/crc32"
+	"sort"
 	"strconv"
 )
 
@@ -10,9 +11,18 @@ type (
 		prefix           string
 		handlerContainer map[uint32]Handler
 		routerMiddleware map[uint32][]Middleware
+		policies         map[uint32][]*Policy
+		patterns         map[uint32]string
 		middleware       []Middleware
 	}
 
+	// RouteInfo 路由表中的一条路由
+	RouteInfo struct {
+		Pattern  string
+		Operator uint32
+		Policies []string
+	}
+
 	LinkRouter func(*Router)
 )
 
@@ -20,6 +30,8 @@ func NewRouter() *Router {
 	return &Router{
 		handlerContainer: make(map[uint32]Handler),
 		routerMiddleware: make(map[uint32][]Middleware),
+		policies:         make(map[uint32][]*Policy),
+		patterns:         make(map[uint32]string),
 	}
 }
 
@@ -44,17 +56,24 @@ func (r *Router) NSRoute(pattern string, handler Handler, middleware ...Middlewa
 	}
 }
 
-// 注册路由，路由中间件
+// 注册路由，路由中间件，访问策略在全局中间件之后执行，保证可以获取到认证中间件保存的身份信息
 func (r *Router) Route(pattern string, handler Handler, middleware ...Middleware) *Router {
 	operator := crc32.ChecksumIEEE([]byte(pattern))
 	if operator <= OperatorMax {
 		panic("Unavailable operator, the value of crc32 need less than " + strconv.Itoa(OperatorMax))
 	}
 
-	r.routerMiddleware[operator] = append(r.routerMiddleware[operator], middleware...)
+	for _, m := range middleware {
+		if p, ok := m.(*Policy); ok {
+			r.policies[operator] = append(r.policies[operator], p)
+		} else {
+			r.routerMiddleware[operator] = append(r.routerMiddleware[operator], m)
+		}
+	}
 
 	if _, ok := r.handlerContainer[operator]; !ok {
 		r.handlerContainer[operator] = handler
+		r.patterns[operator] = pattern
 	}
 
 	return r
@@ -66,3 +85,22 @@ func (r *Router) Use(middleware ...Middleware) *Router {
 
 	return r
 }
+
+// 获取路由表，包含每条路由的访问策略，按照路由排序
+func (r *Router) Routes() []RouteInfo {
+	list := make([]RouteInfo, 0, len(r.patterns))
+	for operator, pattern := range r.patterns {
+		ri := RouteInfo{Pattern: pattern, Operator: operator}
+		for _, p := range r.policies[operator] {
+			ri.Policies = append(ri.Policies, p.String())
+		}
+
+		list = append(list, ri)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Pattern < list[j].Pattern
+	})
+
+	return list
+}
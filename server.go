@@ -1,8 +1,15 @@
 package linker
 
 import (
+	"context"
+	"io"
+	"sync"
+
+	uuid "github.com/satori/go.uuid"
 	"github.com/wpajqz/linker/broker/memory"
 	"github.com/wpajqz/linker/codec"
+	"github.com/wpajqz/linker/discover"
+	pm "github.com/wpajqz/linker/presence/memory"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -10,12 +17,16 @@ const (
 	OperatorHeartbeat = iota
 	OperatorRegisterListener
 	OperatorRemoveListener
-	OperatorMax = 1024
+	OperatorCancel // 取消帧，序列号与需要取消的请求相同，服务端不返回响应
+	OperatorBatch  // 批量请求，请求体中携带多个子请求，响应体中按照顺序返回每个子请求的结果
+	OperatorMax    = 1024
 )
 
 const (
 	NetworkTCP = "tcp"
 	NetworkUDP = "udp"
+
+	NetworkWebSocket = "websocket"
 )
 
 const (
@@ -31,25 +42,62 @@ type (
 	HandlerFunc func(Context)
 
 	Server struct {
-		options Options
-		router  *Router
+		options     Options
+		router      *Router
+		workers     limiter
+		connections *Connections
+		presence    *Presence
+		id          string
+		mu          sync.Mutex
+		instances   []discover.Instance
+		listeners   []func(ctx context.Context) error
+		closers     []func() error
+		requests    int64
+		shutdown    chan struct{}
 	}
 )
 
 func NewServer(opts ...Option) *Server {
 	options := Options{
-		debug:       false,
-		udpPayload:  4096,
-		contentType: codec.JSON,
-		broker:      memory.NewBroker(),
-		tcpEndpoint: &Endpoint{Address: "localhost:8080"},
+		debug:              false,
+		udpPayload:         4096,
+		writeQueueSize:     1024,
+		heartbeatTolerance: 3,
+		contentType:        codec.JSON,
+		broker:             memory.NewBroker(),
+		presenceStore:      pm.NewStore(),
+		tcpEndpoint:        &Endpoint{Address: "localhost:8080"},
+		serviceName:        "linker",
+		serviceWeight:      1,
+		maxBatchSize:       64,
 	}
 
 	for _, o := range opts {
 		o(&options)
 	}
 
-	return &Server{options: options}
+	p := &Presence{store: options.presenceStore, broker: options.broker}
+
+	s := &Server{
+		options:     options,
+		workers:     newLimiter(options.workerPoolSize),
+		connections: newConnections(p),
+		presence:    p,
+		id:          uuid.NewV4().String(),
+		shutdown:    make(chan struct{}),
+	}
+
+	// 在线状态存储需要释放资源时，在关闭所有连接以后释放
+	if closer, ok := options.presenceStore.(io.Closer); ok {
+		s.onShutdown(nil, closer.Close)
+	}
+
+	return s
+}
+
+// 获取当前节点上的连接注册表
+func (s *Server) Connections() *Connections {
+	return s.connections
 }
 
 func (s *Server) Run() error {
@@ -76,6 +124,11 @@ func (s *Server) Run() error {
 	return eg.Wait()
 }
 
+// 获取集群范围内的在线状态
+func (s *Server) Presence() *Presence {
+	return s.presence
+}
+
 // 绑定路由
 func (s *Server) BindRouter(r *Router) {
 	s.registerInternalRouter(r)
@@ -97,6 +150,10 @@ func (s *Server) registerInternalRouter(r *Router) *Router {
 			ctx.Error(StatusInternalServerError, err.Error())
 		}
 
+		if internalTopic(topic) {
+			ctx.Error(StatusForbidden, StatusText(StatusForbidden))
+		}
+
 		if err := ctx.Subscribe(topic, func(bytes []byte) {
 			if _, err := ctx.Write(topic, bytes); err != nil {
 				ctx.Error(StatusInternalServerError, err.Error())
@@ -118,6 +175,10 @@ func (s *Server) registerInternalRouter(r *Router) *Router {
 			ctx.Error(StatusInternalServerError, err.Error())
 		}
 
+		if internalTopic(topic) {
+			ctx.Error(StatusForbidden, StatusText(StatusForbidden))
+		}
+
 		if err := ctx.UnSubscribe(topic); err != nil {
 			ctx.Error(StatusInternalServerError, err.Error())
 		}
@@ -126,6 +187,71 @@ func (s *Server) registerInternalRouter(r *Router) *Router {
 	return r
 }
 
+// serve 依次执行路由中间件、全局中间件和访问策略，然后交给路由对应的handler处理
+func (s *Server) serve(ctx Context, rp Packet) {
+	defer func() {
+		if r := recover(); r != nil {
+			var errMsg string
+
+			switch v := r.(type) {
+			case string:
+				errMsg = v
+			case error:
+				errMsg = v.Error()
+			default:
+				errMsg = StatusText(StatusInternalServerError)
+			}
+
+			ctx.Set(errorTag, errMsg)
+
+			if s.options.errorHandler != nil {
+				s.options.errorHandler.Handle(ctx)
+			}
+
+			ctx.Error(StatusInternalServerError, errMsg)
+		}
+	}()
+
+	if rp.Operator == OperatorHeartbeat {
+		s.negotiateHeartbeat(ctx)
+
+		if s.options.pingHandler != nil {
+			s.options.pingHandler.Handle(ctx)
+		}
+
+		ctx.Success(nil)
+	}
+
+	if rp.Operator == OperatorBatch {
+		s.serveBatch(ctx)
+	}
+
+	handler, ok := s.router.handlerContainer[rp.Operator]
+	if !ok {
+		ctx.Error(StatusInternalServerError, "server don't register your request.")
+	}
+
+	if rm, ok := s.router.routerMiddleware[rp.Operator]; ok {
+		for _, v := range rm {
+			ctx = v.Handle(ctx)
+		}
+	}
+
+	for _, v := range s.router.middleware {
+		ctx = v.Handle(ctx)
+		if tm, ok := v.(TerminateMiddleware); ok {
+			tm.Terminate(ctx)
+		}
+	}
+
+	for _, p := range s.router.policies[rp.Operator] {
+		ctx = p.Handle(ctx)
+	}
+
+	handler.Handle(ctx)
+	ctx.Success(nil) // If it don't call the function of Success or Error, deal it by default
+}
+
 func (f HandlerFunc) Handle(ctx Context) {
 	f(ctx)
 }
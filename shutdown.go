@@ -0,0 +1,93 @@
+package linker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Shutdown 优雅关闭服务：先从服务发现中注销，再停止接收新的连接，
+// 等待正在处理的请求完成以后关闭所有连接，ctx结束时不再等待
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	select {
+	case <-s.shutdown:
+		s.mu.Unlock()
+		return nil
+	default:
+		close(s.shutdown)
+	}
+
+	listeners := s.listeners
+	s.listeners = nil
+	s.mu.Unlock()
+
+	var err error
+	if s.options.registry != nil {
+		err = s.deregister(ctx)
+	}
+
+	for _, closer := range listeners {
+		_ = closer(ctx)
+	}
+
+	// 等待正在处理的请求完成
+wait:
+	for atomic.LoadInt64(&s.requests) > 0 {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break wait
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// 已经写入队列的响应发送完成以后再断开连接
+	s.connections.Range(func(c *Connection) bool {
+		go c.drain(ctx)
+		return true
+	})
+
+	// 等待连接的销毁流程执行完成
+	for s.connections.Count() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	s.mu.Lock()
+	closers := s.closers
+	s.closers = nil
+	s.mu.Unlock()
+
+	for _, closer := range closers {
+		_ = closer()
+	}
+
+	return err
+}
+
+// onShutdown 注册关闭服务时需要执行的操作，listener在等待请求处理之前关闭，closer在关闭所有连接之后执行
+func (s *Server) onShutdown(listener func(ctx context.Context) error, closer func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if listener != nil {
+		s.listeners = append(s.listeners, listener)
+	}
+
+	if closer != nil {
+		s.closers = append(s.closers, closer)
+	}
+}
+
+func (s *Server) isShutdown() bool {
+	select {
+	case <-s.shutdown:
+		return true
+	default:
+		return false
+	}
+}
@@ -8,26 +8,67 @@ import (
 	"time"
 
 	uuid "github.com/satori/go.uuid"
-	"github.com/wpajqz/linker/utils/convert"
 )
 
 func (s *Server) handleTCPConnection(conn *net.TCPConn) error {
-	ctx := &ContextTcp{common: common{Context: context.Background()}, Conn: conn}
+	// 准入判断需要先读取客户端发送的第一个数据包
+	var first *Packet
+	if s.options.acceptHandler != nil {
+		rp, err := s.readTCPPacket(conn)
+		if err != nil {
+			_ = conn.Close()
+			return err
+		}
+
+		info := newConnInfo(NetworkTCP, conn.LocalAddr().String(), conn.RemoteAddr().String(), nil, rp)
+		if p, err := s.accept(info, rp); err != nil {
+			if p != nil {
+				_, _ = conn.Write(p.Bytes())
+			}
+
+			_ = conn.Close()
+			return nil
+		}
+
+		first = &rp
+	}
+
+	writer := newConnWriter(s.options, func(frames [][]byte) error {
+		if err := conn.SetWriteDeadline(s.options.writeDeadline()); err != nil {
+			return err
+		}
+
+		b := net.Buffers(frames)
+		_, err := b.WriteTo(conn)
+		return err
+	}, conn.Close)
+
+	id := uuid.NewV4().String()
+	connection := newConnection(id, NetworkTCP, conn.LocalAddr(), conn.RemoteAddr(), s.options, writer, conn.Close)
+	s.connections.add(connection)
+
+	ctx := &ContextTcp{common: common{Context: context.Background(), options: s.options, connection: connection}, Conn: conn}
+	ctx.Set(nodeID, id)
+
 	if s.options.constructHandler != nil {
 		s.options.constructHandler.Handle(ctx)
 	}
 
-	ctx.Set(nodeID, uuid.NewV4().String())
+	// 每个请求都基于连接的context创建，请求中通过Set保存的数据不会带到后续的请求中
+	base := ctx.Context
 
 	defer func() {
 		if s.options.destructHandler != nil {
 			s.options.destructHandler.Handle(ctx)
 		}
 
+		s.connections.remove(id)
+
 		if err := ctx.UnSubscribeAll(); err != nil {
 			ctx.Error(StatusInternalServerError, err.Error())
 		}
 
+		writer.Close()
 		_ = conn.Close()
 	}()
 
@@ -45,116 +86,58 @@ func (s *Server) handleTCPConnection(conn *net.TCPConn) error {
 		}
 	}
 
-	var (
-		bType         = make([]byte, 4)
-		bSequence     = make([]byte, 8)
-		bHeaderLength = make([]byte, 4)
-		bBodyLength   = make([]byte, 4)
-		sequence      int64
-		headerLength  uint32
-		bodyLength    uint32
-	)
+	idle := make(chan struct{})
+	defer close(idle)
+	go s.watchIdle(ctx, connection, idle)
 
 	for {
-		if s.options.timeout != 0 {
-			err := conn.SetDeadline(time.Now().Add(s.options.timeout))
+		var rp Packet
+		if first != nil {
+			rp, first = *first, nil
+		} else {
+			var err error
+			rp, err = s.readTCPPacket(conn)
 			if err != nil {
 				return err
 			}
 		}
 
-		if _, err := io.ReadFull(conn, bType); err != nil {
-			return err
-		}
-
-		if _, err := io.ReadFull(conn, bSequence); err != nil {
-			return err
-		}
-
-		if _, err := io.ReadFull(conn, bHeaderLength); err != nil {
-			return err
-		}
+		connection.touch()
 
-		if _, err := io.ReadFull(conn, bBodyLength); err != nil {
-			return err
+		if rp.Operator == OperatorCancel {
+			connection.cancel(rp.Sequence)
+			continue
 		}
 
-		sequence = convert.BytesToInt64(bSequence)
-		headerLength = convert.BytesToUint32(bHeaderLength)
-		bodyLength = convert.BytesToUint32(bBodyLength)
+		// 请求使用单独的context，ctx始终是连接的context，连接断开时的清理不依赖最后一个请求
+		pctx := NewContextTcp(base, conn, rp.Operator, rp.Sequence, rp.Header, rp.Body, s.options)
+		pctx.connection = connection
+		pctx.cancelCtx = connection.track(rp.Sequence)
 
-		header := make([]byte, headerLength)
-		if _, err := io.ReadFull(conn, header); err != nil {
-			return err
-		}
+		accepted := s.dispatch(connection.limiter, s.options.inOrder, pctx, func() {
+			defer connection.untrack(rp.Sequence)
+			s.handleTCPPacket(pctx, rp)
+		})
 
-		body := make([]byte, bodyLength)
-		if _, err := io.ReadFull(conn, body); err != nil {
-			return err
+		if !accepted {
+			connection.untrack(rp.Sequence)
 		}
-
-		rp, err := NewPacket(convert.BytesToUint32(bType), sequence, header, body, s.options.pluginForPacketReceiver)
-		if err != nil {
-			return err
-		}
-
-		ctx = NewContextTcp(ctx.Context, conn, rp.Operator, rp.Sequence, rp.Header, rp.Body, s.options)
-		go s.handleTCPPacket(ctx, rp)
 	}
 }
 
-func (s *Server) handleTCPPacket(ctx Context, rp Packet) {
-	defer func() {
-		if r := recover(); r != nil {
-			var errMsg string
-
-			switch v := r.(type) {
-			case string:
-				errMsg = v
-			case error:
-				errMsg = v.Error()
-			default:
-				errMsg = StatusText(StatusInternalServerError)
-			}
-
-			ctx.Set(errorTag, errMsg)
-
-			if s.options.errorHandler != nil {
-				s.options.errorHandler.Handle(ctx)
-			}
-
-			ctx.Error(StatusInternalServerError, errMsg)
-		}
-	}()
-
-	if rp.Operator == OperatorHeartbeat {
-		if s.options.pingHandler != nil {
-			s.options.pingHandler.Handle(ctx)
-		}
-
-		ctx.Success(nil)
-	}
-
-	handler, ok := s.router.handlerContainer[rp.Operator]
-	if !ok {
-		ctx.Error(StatusInternalServerError, "server don't register your request.")
-	}
-
-	if rm, ok := s.router.routerMiddleware[rp.Operator]; ok {
-		for _, v := range rm {
-			ctx = v.Handle(ctx)
+func (s *Server) readTCPPacket(conn *net.TCPConn) (Packet, error) {
+	if s.options.timeout != 0 {
+		err := conn.SetDeadline(time.Now().Add(s.options.timeout))
+		if err != nil {
+			return Packet{}, err
 		}
 	}
 
-	for _, v := range s.router.middleware {
-		ctx = v.Handle(ctx)
-		if tm, ok := v.(TerminateMiddleware); ok {
-			tm.Terminate(ctx)
-		}
-	}
+	return ReadPacket(conn, s.options.pluginForPacketReceiver)
+}
 
-	handler.Handle(ctx)
-	ctx.Success(nil) // If it don't call the function of Success or Error, deal it by default
+func (s *Server) handleTCPPacket(ctx Context, rp Packet) {
+	s.serve(ctx, rp)
 }
 
 // runTCP 开始运行Tcp服务
@@ -171,8 +154,16 @@ func (s *Server) runTCP(address string) error {
 
 	defer listener.Close()
 
+	s.onShutdown(func(ctx context.Context) error {
+		return listener.Close()
+	}, nil)
+
 	fmt.Printf("Listening and serving TCP on %s\n", address)
 
+	if err := s.register(NetworkTCP, listener.Addr(), nil); err != nil {
+		return err
+	}
+
 	if s.options.api != nil {
 		if err := s.options.api.Dial(NetworkTCP, address); err != nil {
 			return err
@@ -182,6 +173,10 @@ func (s *Server) runTCP(address string) error {
 	for {
 		conn, err := listener.AcceptTCP()
 		if err != nil {
+			if s.isShutdown() {
+				return nil
+			}
+
 			continue
 		}
 
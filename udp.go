@@ -10,12 +10,19 @@ import (
 )
 
 func (s *Server) handleUDPData(conn *net.UDPConn, remote *net.UDPAddr, data []byte, length int) {
+	if length < 20 {
+		return
+	}
+
 	bType := data[0:4]
 	bSequence := data[4:12]
 	bHeaderLength := data[12:16]
 
 	sequence := convert.BytesToInt64(bSequence)
 	headerLength := convert.BytesToUint32(bHeaderLength)
+	if 20+int(headerLength) > length {
+		return
+	}
 
 	header := data[20 : 20+headerLength]
 	body := data[20+headerLength : length]
@@ -25,65 +32,39 @@ func (s *Server) handleUDPData(conn *net.UDPConn, remote *net.UDPAddr, data []by
 		return
 	}
 
+	// udp请求不支持取消
+	if rp.Operator == OperatorCancel {
+		return
+	}
+
 	var ctx Context = NewContextUdp(context.Background(), conn, remote, rp.Operator, rp.Sequence, rp.Header, rp.Body, s.options)
 
 	ctx.Set(nodeID, uuid.NewV4().String())
 
-	defer func() {
-		if r := recover(); r != nil {
-			var errMsg string
-
-			switch v := r.(type) {
-			case string:
-				errMsg = v
-			case error:
-				errMsg = v.Error()
-			default:
-				errMsg = StatusText(StatusInternalServerError)
-			}
-
-			ctx.Set(errorTag, errMsg)
-
-			if s.options.errorHandler != nil {
-				s.options.errorHandler.Handle(ctx)
+	// udp没有连接，只受全局并发限制，准入判断也在处理协程中执行，避免阻塞读取
+	s.dispatch(nil, false, ctx, func() {
+		// udp没有连接的概念，每个数据包都需要进行准入判断
+		info := newConnInfo(NetworkUDP, conn.LocalAddr().String(), remote.String(), nil, rp)
+		if p, err := s.accept(info, rp); err != nil {
+			if p != nil {
+				_, _ = conn.WriteToUDP(p.Bytes(), remote)
 			}
 
-			ctx.Error(StatusInternalServerError, errMsg)
+			return
 		}
 
+		s.handleUDPPacket(ctx, rp)
+	})
+}
+
+func (s *Server) handleUDPPacket(ctx Context, rp Packet) {
+	defer func() {
 		if err := ctx.UnSubscribeAll(); err != nil {
 			ctx.Error(StatusInternalServerError, err.Error())
 		}
 	}()
 
-	if rp.Operator == OperatorHeartbeat {
-		if s.options.pingHandler != nil {
-			s.options.pingHandler.Handle(ctx)
-		}
-
-		ctx.Success(nil)
-	}
-
-	handler, ok := s.router.handlerContainer[rp.Operator]
-	if !ok {
-		ctx.Error(StatusInternalServerError, "server don't register your request.")
-	}
-
-	if rm, ok := s.router.routerMiddleware[rp.Operator]; ok {
-		for _, v := range rm {
-			ctx = v.Handle(ctx)
-		}
-	}
-
-	for _, v := range s.router.middleware {
-		ctx = v.Handle(ctx)
-		if tm, ok := v.(TerminateMiddleware); ok {
-			tm.Terminate(ctx)
-		}
-	}
-
-	handler.Handle(ctx)
-	ctx.Success(nil) // If it don't call the function of Success or Error, deal it by default
+	s.serve(ctx, rp)
 }
 
 // 开始运行Tcp服务
@@ -100,6 +81,9 @@ func (s *Server) runUDP(address string) error {
 
 	defer conn.Close()
 
+	// udp没有连接，等待请求处理完成以后再关闭
+	s.onShutdown(nil, conn.Close)
+
 	fmt.Printf("Listening and serving UDP on %s\n", address)
 
 	if s.options.readBufferSize > 0 {
@@ -116,17 +100,21 @@ func (s *Server) runUDP(address string) error {
 		}
 	}
 
-	if s.options.constructHandler != nil {
-		s.options.constructHandler.Handle(nil)
+	if err := s.register(NetworkUDP, conn.LocalAddr(), nil); err != nil {
+		return err
 	}
 
 	for {
 		var data = make([]byte, s.options.udpPayload)
 		n, remote, err := conn.ReadFromUDP(data)
 		if err != nil {
+			if s.isShutdown() {
+				return nil
+			}
+
 			continue
 		}
 
-		go s.handleUDPData(conn, remote, data, n)
+		s.handleUDPData(conn, remote, data, n)
 	}
 }
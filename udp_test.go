@@ -0,0 +1,67 @@
+package linker_test
+
+import (
+	"bytes"
+	"hash/crc32"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+func TestUDPOnOpen(t *testing.T) {
+	var opened int64
+	address := servertest.Address(t)
+	s := linker.NewServer(
+		linker.WithTCPEndpoint(linker.Endpoint{Address: address}),
+		linker.WithUDPEndpoint(linker.Endpoint{Address: address}),
+		// udp没有连接，不会执行OnOpen，tcp连接执行时ctx可以正常使用
+		linker.WithOnOpen(linker.HandlerFunc(func(ctx linker.Context) {
+			if ctx.Connection().Network() != linker.NetworkTCP {
+				atomic.AddInt64(&opened, 1)
+			}
+		})),
+	)
+
+	router := linker.NewRouter()
+	router.Route("/ping", linker.HandlerFunc(func(ctx linker.Context) {
+		ctx.Success("pong")
+	}))
+	s.BindRouter(router)
+
+	servertest.Run(t, s, address)
+
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	p, err := linker.NewPacket(crc32.ChecksumIEEE([]byte("/ping")), 1, nil, []byte(`"ping"`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Write(p.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, err := conn.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rp, err := linker.ReadPacket(bytes.NewReader(data[:n]), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(rp.Body) != `"pong"` || atomic.LoadInt64(&opened) != 0 {
+		t.Fatalf("unexpected response: %s %s, opened %d", rp.Header, rp.Body, opened)
+	}
+}
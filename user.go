@@ -0,0 +1,40 @@
+package linker
+
+const topicUserPrefix = internalTopicPrefix + "user/"
+
+func userTopic(userID string) string {
+	return topicUserPrefix + userID
+}
+
+// SendToUser 向用户的所有连接推送数据，连接可以分布在集群中的任意节点上
+func (s *Server) SendToUser(userID, operator string, body []byte) error {
+	return s.publishPush(userTopic(userID), pushMessage{Operator: operator, Body: body})
+}
+
+// Bind 将当前连接绑定到用户，一个用户可以同时拥有多个连接
+func (dc *common) Bind(userID string) error {
+	if dc.connection == nil {
+		return ErrorConnectionNotFound
+	}
+
+	c := dc.connection
+	if err := c.subscribePush(userTopic(userID)); err != nil {
+		return err
+	}
+
+	return c.connections.Bind(c.nodeID, userID)
+}
+
+// UnBind 解除当前连接和用户的绑定
+func (dc *common) UnBind(userID string) error {
+	if dc.connection == nil {
+		return ErrorConnectionNotFound
+	}
+
+	c := dc.connection
+	if err := c.connections.UnBind(c.nodeID, userID); err != nil {
+		return err
+	}
+
+	return dc.options.broker.UnSubscribe(c.nodeID, userTopic(userID))
+}
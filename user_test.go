@@ -0,0 +1,140 @@
+package linker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wpajqz/linker"
+	"github.com/wpajqz/linker/broker/memory"
+	"github.com/wpajqz/linker/client/export"
+	"github.com/wpajqz/linker/codec"
+	"github.com/wpajqz/linker/internal/servertest"
+)
+
+func newUserServer(t *testing.T, opts ...linker.Option) (*linker.Server, string) {
+	router := linker.NewRouter()
+	router.Route("/login", linker.HandlerFunc(func(ctx linker.Context) {
+		var userID string
+		if err := ctx.ParseParam(&userID); err != nil {
+			ctx.Error(linker.StatusBadRequest, err.Error())
+		}
+
+		if err := ctx.Bind(userID); err != nil {
+			ctx.Error(linker.StatusInternalServerError, err.Error())
+		}
+
+		ctx.Success(nil)
+	}))
+
+	return servertest.Start(t, router, opts...)
+}
+
+func loginUser(t *testing.T, address, userID string, received chan<- string) *export.Client {
+	c, err := export.NewClient(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = c.Close() })
+
+	c.SetContentType(codec.JSON)
+
+	err = c.AddMessageListener("/notify", export.HandlerFunc(func(header, body []byte) {
+		received <- address + ":" + string(body)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.SyncSend("/login", userID, &statusCallback{t: t})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+type statusCallback struct {
+	t *testing.T
+}
+
+func (sc *statusCallback) OnSuccess(header, body []byte) {}
+
+func (sc *statusCallback) OnError(status int, message string) {
+	sc.t.Errorf("request error: %d %s", status, message)
+}
+
+func (sc *statusCallback) OnStart() {}
+
+func (sc *statusCallback) OnEnd() {}
+
+func TestSendToUser(t *testing.T) {
+	b := memory.NewBroker()
+
+	s1, a1 := newUserServer(t, linker.Broker(b))
+	_, a2 := newUserServer(t, linker.Broker(b))
+
+	received := make(chan string, 4)
+	loginUser(t, a1, "u1", received)
+	loginUser(t, a2, "u1", received)
+	loginUser(t, a2, "u2", received)
+
+	if err := s1.SendToUser("u1", "/notify", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-received:
+			got[msg] = true
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timeout waiting for message, received %v", got)
+		}
+	}
+
+	if !got[a1+":hello"] || !got[a2+":hello"] {
+		t.Fatalf("unexpected messages %v", got)
+	}
+
+	select {
+	case msg := <-received:
+		t.Fatalf("unexpected message %s", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestInternalTopic(t *testing.T) {
+	s, address := newUserServer(t)
+
+	c, err := export.NewClient(address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// 客户端不能直接监听用户和房间的内部topic
+	received := make(chan string, 1)
+	for _, topic := range []string{"/linker/user/alice", "/linker/room/lobby", linker.TopicPresence} {
+		err := c.AddMessageListener(topic, export.HandlerFunc(func(header, body []byte) {
+			received <- string(body)
+		}))
+		if err == nil || err.Error() != linker.StatusText(linker.StatusForbidden) {
+			t.Fatalf("%s: unexpected error %v", topic, err)
+		}
+	}
+
+	if err := s.SendToUser("alice", "/notify", []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Broadcast("lobby", "/notify", []byte("secret"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-received:
+		t.Fatalf("unexpected message %s", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,193 @@
+package linker
+
+import (
+	"sync"
+	"time"
+)
+
+// 写队列已满时对慢速消费者的处理方式
+type SlowConsumerPolicy int
+
+const (
+	SlowConsumerBlock      SlowConsumerPolicy = iota // 阻塞等待队列空闲
+	SlowConsumerDrop                                 // 丢弃当前数据包
+	SlowConsumerDisconnect                           // 断开连接
+)
+
+const (
+	defaultMaxBatchBytes = 64 << 10
+	defaultWriteTimeout  = 10 * time.Second
+)
+
+type (
+	// 每个连接独立的写协程，保证数据包按入队顺序写出，并合并小包减少系统调用
+	connWriter struct {
+		queue    chan []byte
+		done     chan struct{}
+		exited   chan struct{}
+		once     sync.Once
+		interval time.Duration
+		maxBatch int
+		policy   SlowConsumerPolicy
+		flush    func(frames [][]byte) error
+		closer   func() error
+	}
+)
+
+func newConnWriter(options Options, flush func(frames [][]byte) error, closer func() error) *connWriter {
+	size := options.writeQueueSize
+	if size <= 0 {
+		size = 1
+	}
+
+	maxBatch := options.writeBatchSize
+	if maxBatch <= 0 {
+		maxBatch = defaultMaxBatchBytes
+	}
+
+	w := &connWriter{
+		queue:    make(chan []byte, size),
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+		interval: options.flushInterval,
+		maxBatch: maxBatch,
+		policy:   options.slowConsumer,
+		flush:    flush,
+		closer:   closer,
+	}
+
+	go w.run()
+
+	return w
+}
+
+// writeDeadline 每次写出数据的截止时间，写出阻塞的连接不会一直占用写协程和等待写队列的请求
+func (o Options) writeDeadline() time.Time {
+	d := o.writeTimeout
+	if d <= 0 {
+		d = o.timeout
+	}
+
+	if d <= 0 {
+		d = defaultWriteTimeout
+	}
+
+	return time.Now().Add(d)
+}
+
+// Write 将数据包放入写队列，队列已满时按照慢速消费者策略处理
+func (w *connWriter) Write(frame []byte) error {
+	select {
+	case <-w.done:
+		return ErrorConnectionClosed
+	case <-w.exited:
+		return ErrorConnectionClosed
+	default:
+	}
+
+	switch w.policy {
+	case SlowConsumerDrop:
+		select {
+		case w.queue <- frame:
+			return nil
+		default:
+			return ErrorWriteQueueFull
+		}
+	case SlowConsumerDisconnect:
+		select {
+		case w.queue <- frame:
+			return nil
+		default:
+			_ = w.closer()
+			return ErrorWriteQueueFull
+		}
+	default:
+		select {
+		case w.queue <- frame:
+			return nil
+		case <-w.exited:
+			return ErrorConnectionClosed
+		}
+	}
+}
+
+// Close 停止写协程，退出前会把队列中剩余的数据包写出
+func (w *connWriter) Close() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+
+	<-w.exited
+}
+
+func (w *connWriter) run() {
+	defer close(w.exited)
+
+	for {
+		select {
+		case frame := <-w.queue:
+			frames := w.collect([][]byte{frame}, len(frame))
+			if err := w.flush(frames); err != nil {
+				_ = w.closer()
+				return
+			}
+		case <-w.done:
+			w.drain()
+			return
+		}
+	}
+}
+
+// drain 写出队列中剩余的数据包
+func (w *connWriter) drain() {
+	var frames [][]byte
+	for {
+		select {
+		case frame := <-w.queue:
+			frames = append(frames, frame)
+		default:
+			if len(frames) > 0 {
+				_ = w.flush(frames)
+			}
+
+			return
+		}
+	}
+}
+
+// collect 合并队列中等待发送的数据包，直到达到批量上限或刷新间隔到期
+func (w *connWriter) collect(frames [][]byte, size int) [][]byte {
+	var timeout <-chan time.Time
+	if w.interval > 0 {
+		timer := time.NewTimer(w.interval)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	for size < w.maxBatch {
+		if timeout == nil {
+			select {
+			case frame := <-w.queue:
+				frames = append(frames, frame)
+				size += len(frame)
+			default:
+				return frames
+			}
+
+			continue
+		}
+
+		select {
+		case frame := <-w.queue:
+			frames = append(frames, frame)
+			size += len(frame)
+		case <-timeout:
+			return frames
+		case <-w.done:
+			return frames
+		}
+	}
+
+	return frames
+}
@@ -0,0 +1,147 @@
+package linker
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter 第一次写出时阻塞，直到release被关闭
+type blockingWriter struct {
+	mu      sync.Mutex
+	frames  []string
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+	closed  chan struct{}
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{started: make(chan struct{}), release: make(chan struct{}), closed: make(chan struct{}, 1)}
+}
+
+func (bw *blockingWriter) flush(frames [][]byte) error {
+	bw.once.Do(func() {
+		close(bw.started)
+		<-bw.release
+	})
+
+	bw.mu.Lock()
+	for _, frame := range frames {
+		bw.frames = append(bw.frames, string(frame))
+	}
+	bw.mu.Unlock()
+
+	return nil
+}
+
+func (bw *blockingWriter) close() error {
+	select {
+	case bw.closed <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func TestSlowConsumer(t *testing.T) {
+	cases := []struct {
+		policy     SlowConsumerPolicy
+		err        error
+		disconnect bool
+		frames     int
+	}{
+		{SlowConsumerDrop, ErrorWriteQueueFull, false, 2},
+		{SlowConsumerDisconnect, ErrorWriteQueueFull, true, 2},
+		{SlowConsumerBlock, nil, false, 3},
+	}
+
+	for _, c := range cases {
+		bw := newBlockingWriter()
+		w := newConnWriter(Options{writeQueueSize: 1, slowConsumer: c.policy}, bw.flush, bw.close)
+
+		// 第一个数据包阻塞在写出过程中，第二个数据包占满队列
+		if err := w.Write([]byte("1")); err != nil {
+			t.Fatal(err)
+		}
+
+		<-bw.started
+
+		if err := w.Write([]byte("2")); err != nil {
+			t.Fatal(err)
+		}
+
+		result := make(chan error, 1)
+		go func() {
+			result <- w.Write([]byte("3"))
+		}()
+
+		if c.policy == SlowConsumerBlock {
+			select {
+			case err := <-result:
+				t.Fatalf("policy %d: write should block, got %v", c.policy, err)
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			close(bw.release)
+		}
+
+		select {
+		case err := <-result:
+			if err != c.err {
+				t.Fatalf("policy %d: unexpected error %v", c.policy, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("policy %d: write not returned", c.policy)
+		}
+
+		select {
+		case <-bw.closed:
+			if !c.disconnect {
+				t.Fatalf("policy %d: unexpected disconnect", c.policy)
+			}
+		default:
+			if c.disconnect {
+				t.Fatalf("policy %d: connection should be closed", c.policy)
+			}
+		}
+
+		if c.policy != SlowConsumerBlock {
+			close(bw.release)
+		}
+
+		w.Close()
+
+		bw.mu.Lock()
+		if len(bw.frames) != c.frames {
+			t.Fatalf("policy %d: unexpected frames %v", c.policy, bw.frames)
+		}
+
+		for i, frame := range bw.frames {
+			if frame != string(rune('1'+i)) {
+				t.Fatalf("policy %d: unexpected order %v", c.policy, bw.frames)
+			}
+		}
+		bw.mu.Unlock()
+	}
+}
+
+func TestWriteBatchSize(t *testing.T) {
+	flush := func(frames [][]byte) error { return nil }
+	close := func() error { return nil }
+
+	// 合并上限与socket的发送缓冲区大小无关
+	w := newConnWriter(Options{writeBufferSize: 1}, flush, close)
+	defer w.Close()
+
+	if w.maxBatch != defaultMaxBatchBytes {
+		t.Fatalf("unexpected batch size: %d", w.maxBatch)
+	}
+
+	b := newConnWriter(Options{writeBatchSize: 1024}, flush, close)
+	defer b.Close()
+
+	if b.maxBatch != 1024 {
+		t.Fatalf("unexpected batch size: %d", b.maxBatch)
+	}
+}